@@ -0,0 +1,272 @@
+package converter
+
+// Node is an element of a parsed Markdown document. Every node records the
+// position in the input where it starts.
+type Node interface {
+	Pos() Position
+}
+
+// Pos returns p, so that every node embedding a Position is a Node.
+func (p Position) Pos() Position {
+	return p
+}
+
+// Document is the root of a parsed Markdown file. Its children are
+// Paragraphs. FrontMatter is the YAML between the "---" lines at the top of
+// the file, if any, which is not rendered.
+type Document struct {
+	Position
+	FrontMatter string
+	Children    []Node
+}
+
+// Paragraph is everything between two blank lines. Unlike in CommonMark it
+// may contain other blocks, such as headings, lists, tables and code blocks,
+// as well as TextBlocks. A ListItem may contain further Paragraphs.
+type Paragraph struct {
+	Position
+	Children []Node
+}
+
+// Heading is a line starting with one to six '#' followed by a space. ID
+// is the slug of its text, made unique within the document, which
+// wikilinks to the heading link to.
+type Heading struct {
+	Position
+	Level    int
+	ID       string
+	Children []Node
+}
+
+// TextBlock is a run of lines of plain text, separated by SoftBreaks.
+type TextBlock struct {
+	Position
+	Children []Node
+}
+
+// List is a run of lines starting with "- ", or, if Ordered, with a number
+// followed by '.' or ')', such as "1. ". Start is the number of the first
+// item of an ordered list.
+type List struct {
+	Position
+	Ordered bool
+	Start   int
+	Items   []*ListItem
+}
+
+// ListItem is an item of a List. Its children are the inline nodes of its
+// text, followed by the Lists and Paragraphs indented beneath it. Task is
+// true for an item starting with "[ ]" or "[x]", and Checked for "[x]".
+type ListItem struct {
+	Position
+	Task     bool
+	Checked  bool
+	Children []Node
+}
+
+// TaskSummary counts the task list items in a document.
+type TaskSummary struct {
+	Done  int
+	Total int
+}
+
+// Tasks counts the task list items in d, including those in nested lists.
+func (d *Document) Tasks() TaskSummary {
+	var summary TaskSummary
+	countTasks(d.Children, &summary)
+
+	return summary
+}
+
+func countTasks(nodes []Node, summary *TaskSummary) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *Paragraph:
+			countTasks(n.Children, summary)
+		case *Blockquote:
+			countTasks(n.Children, summary)
+		case *Callout:
+			countTasks(n.Children, summary)
+		case *List:
+			for _, item := range n.Items {
+				if item.Task {
+					summary.Total++
+				}
+				if item.Checked {
+					summary.Done++
+				}
+				countTasks(item.Children, summary)
+			}
+		}
+	}
+}
+
+// Blockquote is a run of lines starting with '>'. Its children are the
+// Paragraphs of the text after the '>', which may contain any other block,
+// including another Blockquote.
+type Blockquote struct {
+	Position
+	Children []Node
+}
+
+// Callout is an Obsidian callout: a Blockquote whose first line starts with
+// [!type], such as "> [!note] Title". A '-' or '+' after the ']' makes it
+// Foldable, folded unless Open. Title holds the inline nodes of the rest of
+// the first line, and Children the Paragraphs of the lines after it.
+type Callout struct {
+	Position
+	Type     string
+	Foldable bool
+	Open     bool
+	Title    []Node
+	Children []Node
+}
+
+// ThematicBreak is a line of three or more '-', '*' or '_', which may be
+// separated by spaces.
+type ThematicBreak struct {
+	Position
+}
+
+// CodeBlock is a block of lines fenced by "```". Info is whatever follows the
+// opening fence, usually the name of a programming language.
+type CodeBlock struct {
+	Position
+	Info  string
+	Lines []string
+}
+
+// Table is a run of lines starting with '|'. The line after the header may be
+// a delimiter row, such as |--|--|, which is not kept.
+type Table struct {
+	Position
+	Header *TableRow
+	Rows   []*TableRow
+}
+
+// TableRow is one line of a Table.
+type TableRow struct {
+	Position
+	Cells []*TableCell
+}
+
+// TableCell is the text between two '|' in a TableRow. Header is true for
+// the cells of the first row of a Table.
+type TableCell struct {
+	Position
+	Header   bool
+	Children []Node
+}
+
+// FootnoteDefinition is a line starting with "[^n]:". Number is the number
+// the footnote was given by the references to it, in order of appearance.
+type FootnoteDefinition struct {
+	Position
+	Label    string
+	Number   int
+	Children []Node
+}
+
+// Text is plain text, which is escaped when rendered.
+type Text struct {
+	Position
+	Value string
+}
+
+// SoftBreak is the end of a line within a TextBlock.
+type SoftBreak struct {
+	Position
+}
+
+// Emphasis is text surrounded by '*'.
+type Emphasis struct {
+	Position
+	Children []Node
+}
+
+// Strong is text surrounded by "**".
+type Strong struct {
+	Position
+	Children []Node
+}
+
+// Strikethrough is text surrounded by "~~".
+type Strikethrough struct {
+	Position
+	Children []Node
+}
+
+// Highlight is text surrounded by "==".
+type Highlight struct {
+	Position
+	Children []Node
+}
+
+// Superscript is text surrounded by '^'.
+type Superscript struct {
+	Position
+	Children []Node
+}
+
+// Subscript is text surrounded by '~'.
+type Subscript struct {
+	Position
+	Children []Node
+}
+
+// CodeSpan is text surrounded by '`'.
+type CodeSpan struct {
+	Position
+	Value string
+}
+
+// FootnoteReference is a "[^n]" in text. Number is its position among all
+// the footnote references in the document, starting at 1.
+type FootnoteReference struct {
+	Position
+	Label  string
+	Number int
+}
+
+// Link is a link written inline, [text](destination "title"), or by
+// reference, [text][label], [text][] or [text], to a definition
+// [label]: destination "title" elsewhere in the document. Reference is the
+// label of a reference link, and Source what followed its text, such as
+// "[label]". Undefined is true while no definition of Reference has been
+// read, and at the end of the document if there is none.
+type Link struct {
+	Position
+	Destination string
+	Title       string
+	Reference   string
+	Source      string
+	Undefined   bool
+	Children    []Node
+}
+
+// WikiLink is an Obsidian link to another note: [[Note]], [[Note|text]] or
+// [[Note#Heading]]. Its children are the text, or else the name of the note
+// and heading. Destination is the URL given by Options.NoteResolver, and
+// Unresolved is true if it did not know the note.
+type WikiLink struct {
+	Position
+	Note        string
+	Heading     string
+	Destination string
+	Unresolved  bool
+	Children    []Node
+}
+
+// Image is an Obsidian embed of the form ![[image_name.png]], or a Markdown
+// image, ![alt text](path "title"). Name is the path of the image, which is
+// relative to Options.ImageDirectory unless it is an absolute URL. Width and
+// Height are 0 unless given by an embed, as are Caption and its Alt text.
+type Image struct {
+	Position
+	Name    string
+	Alt     string
+	Title   string
+	Width   int
+	Height  int
+	Caption []Node
+}
@@ -0,0 +1,234 @@
+// Package converter turns a text file written in a subset of (Obsidian
+// flavoured) Markdown into the HTML used for blog posts.
+package converter
+
+import (
+	"bufio"
+	"io"
+)
+
+// Defaults used by DefaultOptions, and in place of any empty string field
+// passed to New.
+const (
+	DefaultImageDirectory = "/directory_name"
+	DefaultTableClass     = "table is-hoverable"
+	DefaultFigureClass    = "image"
+)
+
+// Options controls how a Converter renders Markdown.
+type Options struct {
+	// ImageDirectory is prefixed to the name of every embedded image.
+	ImageDirectory string
+
+	// TableClass is the class attribute given to <table> elements.
+	TableClass string
+
+	// FigureClass is the class attribute given to the <figure> wrapping
+	// each image.
+	FigureClass string
+
+	// Entities keeps the character references written in the Markdown, such
+	// as &copy; and &#169;, rather than escaping their '&' as "&amp;".
+	Entities bool
+
+	// Typography turns dashes, ellipses and quotes written in plain text
+	// into their typographic forms. Code is always left as it is.
+	Typography Typography
+
+	// Quotes are the curly quotes used by TypographyFull. When empty,
+	// EnglishQuotes are used. LocaleQuotes gives those of other languages.
+	Quotes Quotes
+
+	// Footnotes enables [^n] references and [^n]: definitions.
+	Footnotes bool
+
+	// Tables enables |-delimited tables.
+	Tables bool
+
+	// Images enables ![[image_name.png]] embeds.
+	Images bool
+
+	// Strikethrough enables ~~struck~~ text.
+	Strikethrough bool
+
+	// Highlights enables Obsidian ==highlighted== text.
+	Highlights bool
+
+	// Superscripts enables ^sup^ text, and Subscripts ~sub~ text. Neither
+	// may contain spaces.
+	Superscripts bool
+	Subscripts   bool
+
+	// Callouts enables Obsidian callouts: blockquotes starting with
+	// [!type], such as "> [!note] Title".
+	Callouts bool
+
+	// CalloutClasses maps the type of a callout, in lower case, to the
+	// class attribute of the element wrapping it. Types which are not in
+	// the map are given "callout callout-" followed by the type.
+	CalloutClasses map[string]string
+
+	// Wikilinks enables Obsidian links between notes: [[Note]],
+	// [[Note|text]] and [[Note#Heading]].
+	Wikilinks bool
+
+	// NoteResolver gives the URL of the note named in a wikilink. When nil,
+	// RelativeResolver{Extension: ".html"} is used, which matches the names
+	// of the files written by the command-line tool's build command.
+	NoteResolver NoteResolver
+
+	// Renderer writes the parsed document. When nil, HTMLRenderer is used.
+	Renderer Renderer
+}
+
+// DefaultOptions returns the options used by the command-line tool, with
+// every feature enabled.
+func DefaultOptions() Options {
+	return Options{
+		ImageDirectory: DefaultImageDirectory,
+		TableClass:     DefaultTableClass,
+		FigureClass:    DefaultFigureClass,
+		Entities:       true,
+		Typography:     TypographyBasic,
+		Footnotes:      true,
+		Tables:         true,
+		Images:         true,
+		Strikethrough:  true,
+		Highlights:     true,
+		Superscripts:   true,
+		Subscripts:     true,
+		Callouts:       true,
+		CalloutClasses: DefaultCalloutClasses(),
+		Wikilinks:      true,
+	}
+}
+
+// DefaultCalloutClasses returns the classes of the callout types Obsidian
+// treats as another name for one of its own, such as "tip" for "hint".
+func DefaultCalloutClasses() map[string]string {
+	aliases := map[string]string{
+		"summary":   "abstract",
+		"tldr":      "abstract",
+		"hint":      "tip",
+		"important": "tip",
+		"check":     "success",
+		"done":      "success",
+		"help":      "question",
+		"faq":       "question",
+		"caution":   "warning",
+		"attention": "warning",
+		"fail":      "failure",
+		"missing":   "failure",
+		"error":     "danger",
+		"cite":      "quote",
+	}
+
+	classes := map[string]string{}
+	for alias, calloutType := range aliases {
+		classes[alias] = "callout callout-" + calloutType
+	}
+
+	return classes
+}
+
+// Converter converts Markdown documents to HTML.
+type Converter struct {
+	opts Options
+}
+
+// New returns a Converter using opts. Empty string fields are replaced with
+// their defaults.
+func New(opts Options) *Converter {
+	if opts.ImageDirectory == "" {
+		opts.ImageDirectory = DefaultImageDirectory
+	}
+	if opts.TableClass == "" {
+		opts.TableClass = DefaultTableClass
+	}
+	if opts.FigureClass == "" {
+		opts.FigureClass = DefaultFigureClass
+	}
+	if opts.NoteResolver == nil {
+		opts.NoteResolver = RelativeResolver{Extension: ".html"}
+	}
+
+	return &Converter{opts: opts}
+}
+
+// maxHeldParagraphs is the most paragraphs Convert holds back waiting for
+// the definitions of their reference links.
+const maxHeldParagraphs = 100
+
+// Convert reads a Markdown document from r and writes its HTML to w. The
+// document is converted a paragraph at a time, so only the paragraph being
+// converted is held in memory. A paragraph with a reference link defined
+// further on is held back, with those after it, until the definition is
+// read, or until maxHeldParagraphs are held, when the link is written as it
+// is. A paragraph with a shortcut reference link, [label], which is not yet
+// defined is only held until the next paragraph is read.
+func (c *Converter) Convert(r io.Reader, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	p := newParser(r, c.opts)
+	p.streaming = true
+	ctx := newRenderContext(bw, c.opts)
+
+	doc := &Document{Position: Position{Line: 1, Column: 1}, FrontMatter: p.frontMatter}
+	if err := ctx.renderer.DocumentStart(ctx, doc); err != nil {
+		return err
+	}
+
+	var prev Node
+	var held []*Paragraph
+	for {
+		para, err := p.nextParagraph()
+		if err != nil {
+			return err
+		}
+		if para != nil {
+			held = append(held, para)
+			if len(p.undefinedLinks) > 0 && len(held) < maxHeldParagraphs {
+				continue
+			}
+		}
+		if len(held) >= maxHeldParagraphs {
+			p.dropUndefinedLinks()
+		}
+
+		// Only the shortcut links of the last paragraph can still be
+		// waiting for their definitions.
+		n := len(held)
+		if para != nil && len(p.shortcutLinks) > 0 {
+			n--
+		}
+		for _, para := range held[:n] {
+			if err := ctx.renderChild(para, prev, documentSeparator); err != nil {
+				return err
+			}
+			prev = para
+		}
+		held = append(held[:0], held[n:]...)
+
+		if para == nil {
+			break
+		}
+	}
+
+	if err := ctx.renderer.DocumentEnd(ctx, doc); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// Parse reads a whole Markdown document from r and returns its tree, which
+// can be inspected or changed before being passed to Render. Unlike
+// Convert, every reference link uses the definitions anywhere in the
+// document.
+func (c *Converter) Parse(r io.Reader) (*Document, error) {
+	return newParser(r, c.opts).parse()
+}
+
+// Render writes doc to w as HTML.
+func (c *Converter) Render(w io.Writer, doc *Document) error {
+	return newRenderContext(w, c.opts).Render(doc)
+}
@@ -0,0 +1,878 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testCase struct {
+	name   string
+	input  string
+	output string
+}
+
+func TestConvert(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:   "an empty string should be returned as-is",
+			input:  "",
+			output: "",
+		},
+		{
+			name:   "a single line with no markdown character should be returned as-is",
+			input:  "This is a plain text file.",
+			output: "This is a plain text file.",
+		},
+		{
+			name:   "a line starting with # should be in <h1> tags",
+			input:  "# This is an h1 header",
+			output: "<h1 id=\"this-is-an-h1-header\"> This is an h1 header</h1>",
+		},
+		{
+			name:   "a line starting with ## should be in <h2> tags",
+			input:  "## This is an h2 header",
+			output: "<h2 id=\"this-is-an-h2-header\"> This is an h2 header</h2>",
+		},
+		{
+			name:   "a line starting with ### should be in <h3> tags",
+			input:  "### This is an h3 header",
+			output: "<h3 id=\"this-is-an-h3-header\"> This is an h3 header</h3>",
+		},
+		{
+			name:   "'#' tags in a header should be returned as-is",
+			input:  "### This is an ### h3 ### header",
+			output: "<h3 id=\"this-is-an-h3-header\"> This is an ### h3 ### header</h3>",
+		},
+		{
+			name:   "text across multiple lines with no markdown should be returned as-is",
+			input:  "This\nis\nsome simple text\nwhich has been spread out\nacross multiple lines.",
+			output: "This\nis\nsome simple text\nwhich has been spread out\nacross multiple lines.",
+		},
+		{
+			name:   "paragraph tags should be added if there is a blank line before a line of plain text",
+			input:  "This is a first line.\n\nParagraph one.",
+			output: "This is a first line.\n<p>\nParagraph one.\n</p>",
+		},
+		{
+			name:   "paragraph tags should be added if there is a blank line before a line of plain text for multiple paragraphs",
+			input:  "This is a first line.\n\nParagraph one.\n\nParagraph two.",
+			output: "This is a first line.\n<p>\nParagraph one.\n</p>\n<p>\nParagraph two.\n</p>",
+		},
+		{
+			name:   "italics tags should be added if a string is surrounded by '*'",
+			input:  "*italic text*",
+			output: "<i>italic text</i>",
+		},
+		{
+			name:   "bold tags should be added if a string is surrounded by '**'",
+			input:  "**bold text**",
+			output: "<b>bold text</b>",
+		},
+		{
+			name:   "italics and bold tags should be added if a string is surrounded by '***'",
+			input:  "***bold text***",
+			output: "<i><b>bold text</b></i>",
+		},
+		{
+			name:   "italics and bold tags may be nested in each other",
+			input:  "*a **b** c* and ***a* b**",
+			output: "<i>a <b>b</b> c</i> and <b><i>a</i> b</b>",
+		},
+		{
+			name:   "italics and bold tags should be added if a string is surrounded by '_' or '__'",
+			input:  "_italic text_ and __bold text__",
+			output: "<i>italic text</i> and <b>bold text</b>",
+		},
+		{
+			name:   "'_' within a word should not add tags but '*' should",
+			input:  "snake_case_name and un*frigging*believable",
+			output: "snake_case_name and un<i>frigging</i>believable",
+		},
+		{
+			name:   "italics tags should correctly surround text with an '*' in it which has spaces either side",
+			input:  "*This text contains * an asterisk.*",
+			output: "<i>This text contains * an asterisk.</i>",
+		},
+		{
+			name:   "a solitary '*' at the end should not create an italics tag",
+			input:  "*This text contains* an asterisk.*",
+			output: "<i>This text contains</i> an asterisk.*",
+		},
+		{
+			name:   "an unmatched '*' should be kept as-is without affecting the text after it",
+			input:  "Some **unclosed *text\n\nMore *text*.",
+			output: "Some **unclosed *text\n<p>\nMore <i>text</i>.\n</p>",
+		},
+		{
+			name:   "strikethrough, highlight, superscript and subscript tags should be added",
+			input:  "~~struck~~, ==highlighted *and* italic==, x^2^ and H~2~O.",
+			output: "<del>struck</del>, <mark>highlighted <i>and</i> italic</mark>, x<sup>2</sup> and H<sub>2</sub>O.",
+		},
+		{
+			name:   "tildes, equals signs and carets without a closing match or around spaces should be kept as-is",
+			input:  "~5 to ~10 minutes, a == b == c, 2 ^ 3 ^ 4 and ~~~three~~~, ~~~a~~ and ===b==",
+			output: "~5 to ~10 minutes, a == b == c, 2 ^ 3 ^ 4 and ~~~three~~~, ~~~a~~ and ===b==",
+		},
+		{
+			name:   "strikethrough and highlights should work in list items, headings and table cells",
+			input:  "# A ~~draft~~ title\n\n- ==Key== point\n- ~~Old~~ point\n\n| ^1^ | H~2~O |",
+			output: "<h1 id=\"a-draft-title\"> A <del>draft</del> title</h1>\n<p>\n<ul>\n<li> <mark>Key</mark> point</li>\n<li> <del>Old</del> point</li>\n</ul>\n</p>\n<p>\n<table class=\"table is-hoverable\">\n<thead>\n<tr>\n<th> <sup>1</sup> </th>\n<th> H<sub>2</sub>O </th>\n</tr>\n</thead>\n<tbody>\n</tbody>\n</table>\n</p>",
+		},
+		{
+			name:   "a backslash before markdown punctuation should make it a literal character",
+			input:  "\\*not italic\\*, \\_not either\\_, \\`not code\\`, \\~~not struck\\~~, \\[not a link\\](/page) and \\\\ one backslash",
+			output: "*not italic*, _not either_, `not code`, ~~not struck~~, [not a link](/page) and \\ one backslash",
+		},
+		{
+			name:   "a backslash before anything other than markdown punctuation should be kept",
+			input:  "C:\\Users\\me",
+			output: "C:\\Users\\me",
+		},
+		{
+			name:   "an escaped character at the start of a line should not start a block",
+			input:  "\\# Not a heading\n\n\\> Not a quote",
+			output: "# Not a heading\n<p>\n&gt; Not a quote\n</p>",
+		},
+		{
+			name:   "backslash escapes should work in headings, list items and table cells",
+			input:  "# A \\*starred\\* title\n\n- \\[x\\] Not a task\n\n| a \\| b | \\*c\\* |",
+			output: "<h1 id=\"a-starred-title\"> A *starred* title</h1>\n<p>\n<ul>\n<li> [x] Not a task</li>\n</ul>\n</p>\n<p>\n<table class=\"table is-hoverable\">\n<thead>\n<tr>\n<th> a | b </th>\n<th> *c* </th>\n</tr>\n</thead>\n<tbody>\n</tbody>\n</table>\n</p>",
+		},
+		{
+			name:   "backslash escapes should work in link text, destinations and titles",
+			input:  "[a \\] bracket](/a\\)b \"say \\\"hi\\\"\")",
+			output: "<a href=\"/a)b\" title=\"say &quot;hi&quot;\">a ] bracket</a>",
+		},
+		{
+			name:   "reserved characters should be substituted with html entities",
+			input:  "This is a file ' which is <filled> with - HTML \"entities\" of interest.",
+			output: "This is a file &apos; which is &lt;filled&gt; with - HTML &quot;entities&quot; of interest.",
+		},
+		{
+			name:   "ampersands should be escaped unless they start a character reference",
+			input:  "AT&T, &copy; &#169; &#xA9; and &notanentity; & co.",
+			output: "AT&amp;T, &copy; &#169; &#xA9; and &amp;notanentity; &amp; co.",
+		},
+		{
+			name:   "character references in code should be escaped",
+			input:  "`&copy; && x`\n\n```\nif a && b {}\n```",
+			output: "<code>&amp;copy; &amp;&amp; x</code>\n<p>\n<pre><code>\nif a &amp;&amp; b {}\n</code></pre>\n</p>",
+		},
+		{
+			name:   "character references in link destinations and titles should not be escaped twice",
+			input:  "[a](/x?a=1&amp;b=2 \"Tom &amp; Jerry's\")",
+			output: "<a href=\"/x?a=1&amp;b=2\" title=\"Tom &amp; Jerry&#39;s\">a</a>",
+		},
+		{
+			name:   "double and triple hyphens and three dots should be dashes and an ellipsis, but not single hyphens or flags",
+			input:  "Pages 10--20 --- or more... Use --verbose for a well-known flag.",
+			output: "Pages 10–20 — or more… Use --verbose for a well-known flag.",
+		},
+		{
+			name:   "runs of more than three hyphens should be kept as-is",
+			input:  "a ---- b",
+			output: "a ---- b",
+		},
+		{
+			name:   "runs of more than three dots should be kept as-is",
+			input:  "a .... b",
+			output: "a .... b",
+		},
+		{
+			name:   "dashes and ellipses in code should be kept as-is",
+			input:  "`a -- b...`",
+			output: "<code>a -- b...</code>",
+		},
+		{
+			name:   "an empty inline code block should be skipped",
+			input:  "``",
+			output: "",
+		},
+		{
+			name:   "plain text between a code block should be kept as-is",
+			input:  "`This is a simple inline code block`",
+			output: "<code>This is a simple inline code block</code>",
+		},
+		{
+			name:   "code tags should be positioned correctly around an inline code block within another sentence",
+			input:  "This is some text surrounding `and inline code block`.",
+			output: "This is some text surrounding <code>and inline code block</code>.",
+		},
+		{
+			name:   "reserved characters within a code block should be replaced with HTML entities",
+			input:  "This file contains `a code block` with `a number of ' <> - \" ` html entities in it.",
+			output: "This file contains <code>a code block</code> with <code>a number of &apos; &lt;&gt; - &quot; </code> html entities in it.",
+		},
+		{
+			name:   "multi-line plain text within a code block should be kept as-is",
+			input:  "```programming_language\nThis is a multiline code block.\nLine one,\nLine two,\nLine three.\n```",
+			output: "<pre><code>\nThis is a multiline code block.\nLine one,\nLine two,\nLine three.\n</code></pre>",
+		},
+		{
+			name:   "paragraphs of plain text within a code block should be kept as-is (without paragraph tags)",
+			input:  "```some programming language\nThis is a line.\n\nHere is another line. It should not be in paragraph tags.\n\nA final line.\n```",
+			output: "<pre><code>\nThis is a line.\n\nHere is another line. It should not be in paragraph tags.\n\nA final line.\n</code></pre>",
+		},
+		{
+			name:   "a paragraph of plain text with an inline code block in it should wrap the <code> tags around it properly",
+			input:  "This is a line.\n\nParagraph `with a code block` in it.",
+			output: "This is a line.\n<p>\nParagraph <code>with a code block</code> in it.\n</p>",
+		},
+		{
+			name:   "a paragraph of plain text with an inline code block in it should wrap the <code> tags around it properly",
+			input:  "This is a line.\n\nHere is a multi-line code block:\n\n```code\nLine one,\n\nLine two,\n\nline three.\n```\n\nThat's the end of the code block.",
+			output: "This is a line.\n<p>\nHere is a multi-line code block:\n</p>\n<p>\n<pre><code>\nLine one,\n\nLine two,\n\nline three.\n</code></pre>\n</p>\n<p>\nThat&apos;s the end of the code block.\n</p>",
+		},
+		{
+			name:   "a multi-line code block with a directory structure within it should be rendered correctly",
+			input:  "```\n- dashboard\n| - frontend\n| - backend\n```",
+			output: "<pre><code>\n- dashboard\n| - frontend\n| - backend\n</code></pre>",
+		},
+		{
+			name:   "a multi-line code block with a directory structure within it should be rendered correctly",
+			input:  "```\n- dashboard\n| - frontend\n| - backend\n```",
+			output: "<pre><code>\n- dashboard\n| - frontend\n| - backend\n</code></pre>",
+		},
+		{
+			name:   "asterisks within a code block should be left as-is",
+			input:  "```js\nimport * as echarts from 'echarts';\n```",
+			output: "<pre><code>\nimport * as echarts from &apos;echarts&apos;;\n</code></pre>",
+		},
+		{
+			name:   "inline footnotes should be replaced with <a id=\"footnote-anchor-n\" href=\"#footnote-n\">[n]</a>",
+			input:  "Here is a footnote.[^1]",
+			output: "Here is a footnote.<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a>",
+		},
+		{
+			name:   "successive inline footnotes should be replaced with  <a id=\"footnote-anchor-n\" href=\"#footnote-n\">[n]</a> and be numbered correctly",
+			input:  "Here is a footnote[^1] and another footnote.[^2]",
+			output: "Here is a footnote<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a> and another footnote.<a id=\"footnote-anchor-2\" href=\"#footnote-2\">[2]</a>",
+		},
+		{
+			name:   "out-of-order footnote numbers should be updated to be in increasing order",
+			input:  "Here is a footnote[^2] and another footnote.[^1]",
+			output: "Here is a footnote<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a> and another footnote.<a id=\"footnote-anchor-2\" href=\"#footnote-2\">[2]</a>",
+		},
+		{
+			name:   "a footnote in a paragraph should have paragraph and anchor tags added correctly",
+			input:  "# This is a heading\n\nHere is a footnote.[^1]",
+			output: "<h1 id=\"this-is-a-heading\"> This is a heading</h1>\n<p>\nHere is a footnote.<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a>\n</p>",
+		},
+		{
+			name:   "a footnote in a paragraph should have paragraph and anchor tags added correctly, and successive footnotes should be numbered in increasing order",
+			input:  "# This is a heading\n\nHere is a footnote.[^2] Here's another.[^1]",
+			output: "<h1 id=\"this-is-a-heading\"> This is a heading</h1>\n<p>\nHere is a footnote.<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a> Here&apos;s another.<a id=\"footnote-anchor-2\" href=\"#footnote-2\">[2]</a>\n</p>",
+		},
+		{
+			name:   "a footnote in a paragraph and a footnote at the end of the post should have anchor tags added correctly",
+			input:  "Throwaway line\n\nThis paragraph references a footnote.[^1]\n\n[^1]: This is the reference.",
+			output: "Throwaway line\n<p>\nThis paragraph references a footnote.<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a>\n</p>\n\n<p id=\"footnote-1\">\n<a href=\"#footnote-anchor-1\">[1]</a>\n This is the reference.\n</p>",
+		},
+		{
+			name:   "successive footnotes in text and at the end should be numbered correctly",
+			input:  "Throwaway line\n\nThis paragraph references a footnote.[^1]\n\nThis paragraph[^2] also has a footnote.\n\n[^1]: This is the reference.\n[^2]: This is a footnote.",
+			output: "Throwaway line\n<p>\nThis paragraph references a footnote.<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a>\n</p>\n<p>\nThis paragraph<a id=\"footnote-anchor-2\" href=\"#footnote-2\">[2]</a> also has a footnote.\n</p>\n\n<p id=\"footnote-1\">\n<a href=\"#footnote-anchor-1\">[1]</a>\n This is the reference.\n</p>\n<p id=\"footnote-2\">\n<a href=\"#footnote-anchor-2\">[2]</a>\n This is a footnote.\n</p>",
+		},
+		{
+			name:   "'#' in footnotes should not cause header tags to be added",
+			input:  "Throwaway line\n\nThis paragraph references a footnote.[^1]\n\n[^1]: This is the reference, it has a url: https://this-is-not-a-real-url.blue/database?query=#a-query.",
+			output: "Throwaway line\n<p>\nThis paragraph references a footnote.<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a>\n</p>\n\n<p id=\"footnote-1\">\n<a href=\"#footnote-anchor-1\">[1]</a>\n This is the reference, it has a url: https://this-is-not-a-real-url.blue/database?query=#a-query.\n</p>",
+		},
+		{
+			name:   "double-digit footnotes should be numbered correctly",
+			input:  "Throwaway line\n\n[^1]\n[^2]\n[^3]\n[^4]\n[^5]\n[^6]\n[^7]\n[^8]\n[^9]\n[^10]\n[^11]\n[^12]\n\n[^1]: 1\n[^2]: 2\n[^3]: 3\n[^4]: 4\n[^5]: 5\n[^6]: 6\n[^7]: 7\n[^8]: 8\n[^9]: 9\n[^10]: 10\n[^11]: 11\n[^12]: 12",
+			output: "Throwaway line\n\n<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a>\n<a id=\"footnote-anchor-2\" href=\"#footnote-2\">[2]</a>\n<a id=\"footnote-anchor-3\" href=\"#footnote-3\">[3]</a>\n<a id=\"footnote-anchor-4\" href=\"#footnote-4\">[4]</a>\n<a id=\"footnote-anchor-5\" href=\"#footnote-5\">[5]</a>\n<a id=\"footnote-anchor-6\" href=\"#footnote-6\">[6]</a>\n<a id=\"footnote-anchor-7\" href=\"#footnote-7\">[7]</a>\n<a id=\"footnote-anchor-8\" href=\"#footnote-8\">[8]</a>\n<a id=\"footnote-anchor-9\" href=\"#footnote-9\">[9]</a>\n<a id=\"footnote-anchor-10\" href=\"#footnote-10\">[10]</a>\n<a id=\"footnote-anchor-11\" href=\"#footnote-11\">[11]</a>\n<a id=\"footnote-anchor-12\" href=\"#footnote-12\">[12]</a>\n\n<p id=\"footnote-1\">\n<a href=\"#footnote-anchor-1\">[1]</a>\n 1\n</p>\n<p id=\"footnote-2\">\n<a href=\"#footnote-anchor-2\">[2]</a>\n 2\n</p>\n<p id=\"footnote-3\">\n<a href=\"#footnote-anchor-3\">[3]</a>\n 3\n</p>\n<p id=\"footnote-4\">\n<a href=\"#footnote-anchor-4\">[4]</a>\n 4\n</p>\n<p id=\"footnote-5\">\n<a href=\"#footnote-anchor-5\">[5]</a>\n 5\n</p>\n<p id=\"footnote-6\">\n<a href=\"#footnote-anchor-6\">[6]</a>\n 6\n</p>\n<p id=\"footnote-7\">\n<a href=\"#footnote-anchor-7\">[7]</a>\n 7\n</p>\n<p id=\"footnote-8\">\n<a href=\"#footnote-anchor-8\">[8]</a>\n 8\n</p>\n<p id=\"footnote-9\">\n<a href=\"#footnote-anchor-9\">[9]</a>\n 9\n</p>\n<p id=\"footnote-10\">\n<a href=\"#footnote-anchor-10\">[10]</a>\n 10\n</p>\n<p id=\"footnote-11\">\n<a href=\"#footnote-anchor-11\">[11]</a>\n 11\n</p>\n<p id=\"footnote-12\">\n<a href=\"#footnote-anchor-12\">[12]</a>\n 12\n</p>",
+		},
+		{
+			name:   "footnotes at the end should be renumbered if footnotes in text were renumbered",
+			input:  "Throwaway line\n\nThis paragraph references a footnote.[^2]\n\nThis paragraph[^1] also has a footnote.\n\n[^1]: This is the reference.\n[^2]: This is a footnote.",
+			output: "Throwaway line\n<p>\nThis paragraph references a footnote.<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a>\n</p>\n<p>\nThis paragraph<a id=\"footnote-anchor-2\" href=\"#footnote-2\">[2]</a> also has a footnote.\n</p>\n\n<p id=\"footnote-2\">\n<a href=\"#footnote-anchor-2\">[2]</a>\n This is the reference.\n</p>\n<p id=\"footnote-1\">\n<a href=\"#footnote-anchor-1\">[1]</a>\n This is a footnote.\n</p>",
+		},
+		{
+			name:   "unordered lists should have <ul> tags and <li> tags",
+			input:  "# Unordered List!\n\n- This is an unordered list with a - dash.\n- One,\n- Two,\n- Three.",
+			output: "<h1 id=\"unordered-list\"> Unordered List!</h1>\n<p>\n<ul>\n<li> This is an unordered list with a - dash.</li>\n<li> One,</li>\n<li> Two,</li>\n<li> Three.</li>\n</ul>\n</p>",
+		},
+		{
+			name:   "ordered lists should have <ol> tags and <li> tags",
+			input:  "# Steps\n\n1. Open the *file*.\n2. Add a footnote.[^1]\n3) Save it with `:w`.",
+			output: "<h1 id=\"steps\"> Steps</h1>\n<p>\n<ol>\n<li> Open the <i>file</i>.</li>\n<li> Add a footnote.<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a></li>\n<li> Save it with <code>:w</code>.</li>\n</ol>\n</p>",
+		},
+		{
+			name:   "an ordered list should start at the number of its first item",
+			input:  "4. Four\n5. Five",
+			output: "<ol start=\"4\">\n<li> Four</li>\n<li> Five</li>\n</ol>",
+		},
+		{
+			name:   "a change between ordered and unordered items should start a new list",
+			input:  "1. One\n- Dash",
+			output: "<ol>\n<li> One</li>\n</ol>\n<ul>\n<li> Dash</li>\n</ul>",
+		},
+		{
+			name:   "indented list items should be nested in the item above them",
+			input:  "- One\n  - One point one\n  - One point two\n- Two",
+			output: "<ul>\n<li> One\n<ul>\n<li> One point one</li>\n<li> One point two</li>\n</ul>\n</li>\n<li> Two</li>\n</ul>",
+		},
+		{
+			name:   "ordered and unordered lists indented with tabs should nest at any depth",
+			input:  "1. Step\n\t- Detail\n\t\t1. Sub-step\n2. Next",
+			output: "<ol>\n<li> Step\n<ul>\n<li> Detail\n<ol>\n<li> Sub-step</li>\n</ol>\n</li>\n</ul>\n</li>\n<li> Next</li>\n</ol>",
+		},
+		{
+			name:   "indented text after a blank line should be another paragraph of the list item",
+			input:  "- First item.\n\n  More about the first item.\n- Second item.",
+			output: "<ul>\n<li> First item.\n<p>\nMore about the first item.\n</p>\n</li>\n<li> Second item.</li>\n</ul>",
+		},
+		{
+			name:   "list items separated by blank lines should be in the same list",
+			input:  "1. Install it.\n\n   Run the installer.\n\n2. Configure it.\n\n- a\n\n- b",
+			output: "<ol>\n<li> Install it.\n<p>\nRun the installer.\n</p>\n</li>\n<li> Configure it.</li>\n</ol>\n<p>\n<ul>\n<li> a</li>\n<li> b</li>\n</ul>\n</p>",
+		},
+		{
+			name:   "a blank line between list items of different kinds should end the list",
+			input:  "- a\n\n1. b",
+			output: "<ul>\n<li> a</li>\n</ul>\n<p>\n<ol>\n<li> b</li>\n</ol>\n</p>",
+		},
+		{
+			name:   "a list item may contain an indented code block",
+			input:  "- Run:\n\n  ```sh\n  go test\n  ```",
+			output: "<ul>\n<li> Run:\n<p>\n<pre><code>\ngo test\n</code></pre>\n</p>\n</li>\n</ul>",
+		},
+		{
+			name:   "indented text straight after a list item should continue its text",
+			input:  "- A long\n  item.",
+			output: "<ul>\n<li> A long\n  item.</li>\n</ul>",
+		},
+		{
+			name:   "task list items should have disabled checkboxes",
+			input:  "- [ ] To do\n- [x] Done\n- [X] Also done\n- [y] Not a task",
+			output: "<ul>\n<li class=\"task-list-item\"><input type=\"checkbox\" disabled> To do</li>\n<li class=\"task-list-item\"><input type=\"checkbox\" disabled checked> Done</li>\n<li class=\"task-list-item\"><input type=\"checkbox\" disabled checked> Also done</li>\n<li> [y] Not a task</li>\n</ul>",
+		},
+		{
+			name:   "task list items may be nested and contain inline markdown",
+			input:  "1. [ ] Ship *it*\n   - [x] Write `tests`[^1]",
+			output: "<ol>\n<li class=\"task-list-item\"><input type=\"checkbox\" disabled> Ship <i>it</i>\n<ul>\n<li class=\"task-list-item\"><input type=\"checkbox\" disabled checked> Write <code>tests</code><a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a></li>\n</ul>\n</li>\n</ol>",
+		},
+		{
+			name:   "a number not followed by a space should not start a list",
+			input:  "3.14 is close to pi.",
+			output: "3.14 is close to pi.",
+		},
+		{
+			name:   "lines starting with > should be in <blockquote> tags",
+			input:  "> A quote\n> over two lines.\n\nAfter the quote.",
+			output: "<blockquote>\nA quote\nover two lines.\n</blockquote>\n<p>\nAfter the quote.\n</p>",
+		},
+		{
+			name:   "a blockquote may have several paragraphs",
+			input:  "> One.\n>\n> Two.",
+			output: "<blockquote>\nOne.\n<p>\nTwo.\n</p>\n</blockquote>",
+		},
+		{
+			name:   "blockquotes may be nested",
+			input:  "> Outer\n> > Inner",
+			output: "<blockquote>\nOuter\n<blockquote>\nInner\n</blockquote>\n</blockquote>",
+		},
+		{
+			name:   "a blockquote may contain headings, lists and code blocks",
+			input:  "> # Title\n> - one\n> - two\n>\n> ```\n> x > y\n> ```",
+			output: "<blockquote>\n<h1 id=\"title\"> Title</h1>\n<ul>\n<li> one</li>\n<li> two</li>\n</ul>\n<p>\n<pre><code>\nx &gt; y\n</code></pre>\n</p>\n</blockquote>",
+		},
+		{
+			name:   "a > which is not at the start of a line should be escaped",
+			input:  "2 > 1\n\n> Quoted",
+			output: "2 &gt; 1\n<p>\n<blockquote>\nQuoted\n</blockquote>\n</p>",
+		},
+		{
+			name:   "callouts should be in a <div> with a title and body",
+			input:  "> [!note] Read this\n> The body.",
+			output: "<div class=\"callout callout-note\">\n<div class=\"callout-title\">Read this</div>\n<div class=\"callout-content\">\nThe body.\n</div>\n</div>",
+		},
+		{
+			name:   "foldable callouts should be in <details> tags, titled with their type if they have no title",
+			input:  "> [!WARNING]-\n> Hidden *body*.",
+			output: "<details class=\"callout callout-warning\">\n<summary class=\"callout-title\">Warning</summary>\n<div class=\"callout-content\">\nHidden <i>body</i>.\n</div>\n</details>",
+		},
+		{
+			name:   "callouts folded open should be open, and aliases should have the class of their type",
+			input:  "> [!faq]+ Why?",
+			output: "<details class=\"callout callout-question\" open>\n<summary class=\"callout-title\">Why?</summary>\n</details>",
+		},
+		{
+			name:   "a blockquote which does not start with a callout marker should be a blockquote",
+			input:  "> [!not a callout]",
+			output: "<blockquote>\n[!not a callout]\n</blockquote>",
+		},
+		{
+			name:   "inline links should be in <a> tags, with their destination escaped",
+			input:  "A [link](https://example.com/a%20b?x=1&y=2 \"The title\") and [*another*](</my file.html>) and [a quote](/a\"b).",
+			output: "A <a href=\"https://example.com/a%20b?x=1&amp;y=2\" title=\"The title\">link</a> and <a href=\"/my%20file.html\"><i>another</i></a> and <a href=\"/a%22b\">a quote</a>.",
+		},
+		{
+			name:   "links which would run code should link to #",
+			input:  "A [link](javascript:alert(1)), [another](JavaScript:x) and [data](data:text/html,x)",
+			output: "A <a href=\"#\">link</a>, <a href=\"#\">another</a> and <a href=\"#\">data</a>",
+		},
+		{
+			name:   "a % in a link destination which does not start a percent-encoding should be encoded",
+			input:  "[all](/100%) and [half](/50%25)",
+			output: "<a href=\"/100%25\">all</a> and <a href=\"/50%25\">half</a>",
+		},
+		{
+			name:   "reference links should use definitions from later in the document",
+			input:  "See [the docs][docs] and [Docs].\n\n[docs]: https://example.com/docs 'Read them'",
+			output: "See <a href=\"https://example.com/docs\" title=\"Read them\">the docs</a> and <a href=\"https://example.com/docs\" title=\"Read them\">Docs</a>.",
+		},
+		{
+			name:   "reference links defined after too many paragraphs should be written as they are",
+			input:  "See [the docs][docs].\n\n" + strings.Repeat("Text.\n\n", maxHeldParagraphs) + "[docs]: /docs",
+			output: "See [the docs][docs].\n" + strings.TrimSuffix(strings.Repeat("<p>\nText.\n</p>\n", maxHeldParagraphs), "\n"),
+		},
+		{
+			name:   "collapsed reference links should use definitions from earlier in the document",
+			input:  "[Home]: /index.html\n\nGo [home][].\n\nOr [home] again.",
+			output: "Go <a href=\"/index.html\">home</a>.\n<p>\nOr <a href=\"/index.html\">home</a> again.\n</p>",
+		},
+		{
+			name:   "link definitions in a blockquote should not leave an empty paragraph",
+			input:  "> x\n>\n> [a]: /url",
+			output: "<blockquote>\nx\n</blockquote>",
+		},
+		{
+			name:   "link definitions in a list item should not leave an empty paragraph",
+			input:  "- a\n\n  [r]: /x",
+			output: "<ul>\n<li> a</li>\n</ul>",
+		},
+		{
+			name:   "a line which looks like a link definition in the middle of text should be kept",
+			input:  "Release notes.\n[Update]: fixed\n\n[a]: /a\n[b]: /b",
+			output: "Release notes.\n[Update]: fixed",
+		},
+		{
+			name:   "shortcut links should use definitions straight after their paragraph",
+			input:  "See the [Docs].\n\n[docs]: /docs",
+			output: "See the <a href=\"/docs\">Docs</a>.",
+		},
+		{
+			name:   "shortcut links should not wait for definitions after the next paragraph when streaming",
+			input:  "He said [sic] this.\n\nMore.\n\n[sic]: /sic",
+			output: "He said [sic] this.\n<p>\nMore.\n</p>",
+		},
+		{
+			name:   "links which do not match should be written as plain text",
+			input:  "An [undefined][ref] link, [text](no closing paren and [brackets].",
+			output: "An [undefined][ref] link, [text](no closing paren and [brackets].",
+		},
+		{
+			name:   "wikilinks should link to the HTML file of the note",
+			input:  "See [[Other Note]], [[Other Note|that note]], [[Other Note#Some Heading]] or [[#Intro]].",
+			output: "See <a class=\"wikilink\" href=\"Other%20Note.html\">Other Note</a>, <a class=\"wikilink\" href=\"Other%20Note.html\">that note</a>, <a class=\"wikilink\" href=\"Other%20Note.html#some-heading\">Other Note &gt; Some Heading</a> or <a class=\"wikilink\" href=\"#intro\">Intro</a>.",
+		},
+		{
+			name:   "headings should have the ids wikilinks to them link to",
+			input:  "# Some Heading\n\nSee [[#Some Heading]] and [[Other Note#Some Heading]].",
+			output: "<h1 id=\"some-heading\"> Some Heading</h1>\n<p>\nSee <a class=\"wikilink\" href=\"#some-heading\">Some Heading</a> and <a class=\"wikilink\" href=\"Other%20Note.html#some-heading\">Other Note &gt; Some Heading</a>.\n</p>",
+		},
+		{
+			name:   "headings with the same text should have unique ids",
+			input:  "# Notes\n\n## Notes\n\n## Notes 1\n\n### Notes",
+			output: "<h1 id=\"notes\"> Notes</h1>\n<p>\n<h2 id=\"notes-1\"> Notes</h2>\n</p>\n<p>\n<h2 id=\"notes-1-1\"> Notes 1</h2>\n</p>\n<p>\n<h3 id=\"notes-2\"> Notes</h3>\n</p>",
+		},
+		{
+			name:   "wikilinks and images in table cells should keep their '|'",
+			input:  "| a | b |\n|-|-|\n| [[Note|alias]] | ![[img.png|300]] |",
+			output: "<table class=\"table is-hoverable\">\n<thead>\n<tr>\n<th> a </th>\n<th> b </th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td> <a class=\"wikilink\" href=\"Note.html\">alias</a> </td>\n<td> <figure class=\"image\">\n<img src=\"/directory_name/img.png\" width=\"300\">\n</figure> </td>\n</tr>\n</tbody>\n</table>",
+		},
+		{
+			name:   "lines of three or more -, * or _ should be <hr> tags",
+			input:  "Above\n\n---\n\n***\n_ _ _\n- - -\n\nBelow",
+			output: "Above\n<p>\n<hr>\n</p>\n<p>\n<hr>\n<hr>\n<hr>\n</p>\n<p>\nBelow\n</p>",
+		},
+		{
+			name:   "front matter at the top of the file should not be written",
+			input:  "---\ntitle: A *post*\ntags: [a, b]\n---\n\n# A post",
+			output: "<h1 id=\"a-post\"> A post</h1>",
+		},
+		{
+			name:   "front matter may have blank lines in it",
+			input:  "---\ntitle: Post\n\ntags: [a]\n---\n\n# Body",
+			output: "<h1 id=\"body\"> Body</h1>",
+		},
+		{
+			name:   "--- lines around text which is not YAML at the top of the file should be <hr> tags, not front matter",
+			input:  "---\n\n# Title\n\nBody text.\n\n---\n\nMore",
+			output: "<hr>\n<p>\n<h1 id=\"title\"> Title</h1>\n</p>\n<p>\nBody text.\n</p>\n<p>\n<hr>\n</p>\n<p>\nMore\n</p>",
+		},
+		{
+			name:   "a --- line at the top of the file closed only after too many lines should be an <hr> tag",
+			input:  "---\n" + strings.Repeat("key: value\n", maxFrontMatterLines+1) + "---",
+			output: "<hr>\n" + strings.TrimSuffix(strings.Repeat("key: value\n", maxFrontMatterLines+1), "\n") + "\n<hr>",
+		},
+		{
+			name:   "a --- line at the top of the file without a closing line should be an <hr> tag",
+			input:  "---\nNot front matter -- really.",
+			output: "<hr>\nNot front matter – really.",
+		},
+		{
+			name:   "the head of a table should be added correctly",
+			input:  "| Table | Head |",
+			output: "<table class=\"table is-hoverable\">\n<thead>\n<tr>\n<th> Table </th>\n<th> Head </th>\n</tr>\n</thead>\n<tbody>\n</tbody>\n</table>",
+		},
+		{
+			name:   "the border line after the header of a table should be added correctly",
+			input:  "| Table | Head |\n|--|--|",
+			output: "<table class=\"table is-hoverable\">\n<thead>\n<tr>\n<th> Table </th>\n<th> Head </th>\n</tr>\n</thead>\n<tbody>\n</tbody>\n</table>",
+		},
+		{
+			name:   "simple tables without markdown characters in them should have the appropriate table tags added",
+			input:  "| col name one | col name two |\n|-|-|\n| row contents one | row contents two |",
+			output: "<table class=\"table is-hoverable\">\n<thead>\n<tr>\n<th> col name one </th>\n<th> col name two </th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td> row contents one </td>\n<td> row contents two </td>\n</tr>\n</tbody>\n</table>",
+		},
+		{
+			name:   "a table with html entities should have them replaced",
+			input:  "| col name one | col name two |\n|-|-|\n| A non-entity / | Some entities - ' |\n| < More entities > | \"And I quote...\" |",
+			output: "<table class=\"table is-hoverable\">\n<thead>\n<tr>\n<th> col name one </th>\n<th> col name two </th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td> A non-entity / </td>\n<td> Some entities - &apos; </td>\n</tr>\n<tr>\n<td> &lt; More entities &gt; </td>\n<td> &quot;And I quote…&quot; </td>\n</tr>\n</tbody>\n</table>",
+		},
+		{
+			name:   "images should be placed into <figure> and <img> tags",
+			input:  "![[image_name.png]]",
+			output: "<figure class=\"image\">\n<img src=\"/directory_name/image_name.png\">\n</figure>",
+		},
+		{
+			name:   "a size after an embedded image should set its width, and its height",
+			input:  "![[wide.png|300]]\n\n![[box.png|300x200]]",
+			output: "<figure class=\"image\">\n<img src=\"/directory_name/wide.png\" width=\"300\">\n</figure>\n<p>\n<figure class=\"image\">\n<img src=\"/directory_name/box.png\" width=\"300\" height=\"200\">\n</figure>\n</p>",
+		},
+		{
+			name:   "a caption after an embedded image should be its alt text and <figcaption>",
+			input:  "![[cat.png|A *sleepy* cat|400]]",
+			output: "<figure class=\"image\">\n<img src=\"/directory_name/cat.png\" alt=\"A sleepy cat\" width=\"400\">\n<figcaption>A <i>sleepy</i> cat</figcaption>\n</figure>",
+		},
+		{
+			name:   "markdown images should have alt and title attributes, and be in the image directory",
+			input:  "![A *cat*, asleep](<cats/cat 1.png> \"Tom & Jerry\")",
+			output: "<figure class=\"image\">\n<img src=\"/directory_name/cats/cat%201.png\" alt=\"A cat, asleep\" title=\"Tom &amp; Jerry\">\n</figure>",
+		},
+		{
+			name:   "markdown images with absolute URLs should not be in the image directory",
+			input:  "![Logo](https://example.com/logo.png?size=2&dark=1)\n\n![Icon](/icons/icon.svg)",
+			output: "<figure class=\"image\">\n<img src=\"https://example.com/logo.png?size=2&amp;dark=1\" alt=\"Logo\">\n</figure>\n<p>\n<figure class=\"image\">\n<img src=\"/icons/icon.svg\" alt=\"Icon\">\n</figure>\n</p>",
+		},
+		{
+			name:   "markdown images may have data:image/ URLs, but not other data: or script URLs",
+			input:  "![dot](data:image/gif;base64,R0lG) ![page](data:text/html,x) ![script](javascript:alert(1))",
+			output: "<figure class=\"image\">\n<img src=\"data:image/gif;base64,R0lG\" alt=\"dot\">\n</figure> <figure class=\"image\">\n<img src=\"#\" alt=\"page\">\n</figure> <figure class=\"image\">\n<img src=\"#\" alt=\"script\">\n</figure>",
+		},
+		{
+			name:   "an exclamation mark which does not start an image should be kept",
+			input:  "Wow! [a link](/page)!",
+			output: "Wow! <a href=\"/page\">a link</a>!",
+		},
+		{
+			name:   "! at the end of a file should be written correctly.",
+			input:  "A sentence!",
+			output: "A sentence!",
+		},
+		{
+			name:   "html elements in a header should be replaced correctly",
+			input:  "# A header with html < > \" ' - elements",
+			output: "<h1 id=\"a-header-with-html-elements\"> A header with html &lt; &gt; &quot; &apos; - elements</h1>",
+		},
+		{
+			name:   "unordered lists should have their tags closed correctly before the next piece of content",
+			input:  "# Header\n\n- Unordered\n- List\n\nEnd of file.",
+			output: "<h1 id=\"header\"> Header</h1>\n<p>\n<ul>\n<li> Unordered</li>\n<li> List</li>\n</ul>\n</p>\n<p>\nEnd of file.\n</p>",
+		},
+		{
+			name:   "integration test: a small file",
+			input:  "# Introduction\n\n## A Small File\n\nThis is a *small* file. It contains - neigh - requires the program to correctly translate a variety of different Obsidian Markdown elements into the HTML elements I want.\n\n![[image_name.png]]\n\nFor example:\n\n- paragraphs[^1]\n- \"0 < 1\"\n- \"2 > 1\"\n- **and**\n- ***headings***\n- `Code blocks`\n\n```Pseudocode\nfn removeCharacterFromList(remList list, charToRemove char) list {\n    match remList {\n        case x::[]:\n            match x {\n                charToRemove: []\n                _: x\n            }\n        case x::xs:\n            match x {\n                charToRemove: removeCharacterFromList(xs, charToRemove)\n                _: x::removeCharacterFromList(xs, charToRemove)\n            }\n    }\n}\n\nremoveCharacterFromList(['a', 'b', 'c'], 'a')\n```\n\n## A table conclusion\n\nAnother footnote.[^2]\n\n| A table | must have | columns |\n|--|--|--|\n| and rows. | which may have an arbitrary amount of content | |\n\n[^1]: With footnotes!\n[^2]: Pseudocode.",
+			output: "<h1 id=\"introduction\"> Introduction</h1>\n<p>\n<h2 id=\"a-small-file\"> A Small File</h2>\n</p>\n<p>\nThis is a <i>small</i> file. It contains - neigh - requires the program to correctly translate a variety of different Obsidian Markdown elements into the HTML elements I want.\n</p>\n<p>\n<figure class=\"image\">\n<img src=\"/directory_name/image_name.png\">\n</figure>\n</p>\n<p>\nFor example:\n</p>\n<p>\n<ul>\n<li> paragraphs<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a></li>\n<li> &quot;0 &lt; 1&quot;</li>\n<li> &quot;2 &gt; 1&quot;</li>\n<li> <b>and</b></li>\n<li> <i><b>headings</b></i></li>\n<li> <code>Code blocks</code></li>\n</ul>\n</p>\n<p>\n<pre><code>\nfn removeCharacterFromList(remList list, charToRemove char) list {\n    match remList {\n        case x::[]:\n            match x {\n                charToRemove: []\n                _: x\n            }\n        case x::xs:\n            match x {\n                charToRemove: removeCharacterFromList(xs, charToRemove)\n                _: x::removeCharacterFromList(xs, charToRemove)\n            }\n    }\n}\n\nremoveCharacterFromList([&apos;a&apos;, &apos;b&apos;, &apos;c&apos;], &apos;a&apos;)\n</code></pre>\n</p>\n<p>\n<h2 id=\"a-table-conclusion\"> A table conclusion</h2>\n</p>\n<p>\nAnother footnote.<a id=\"footnote-anchor-2\" href=\"#footnote-2\">[2]</a>\n</p>\n<p>\n<table class=\"table is-hoverable\">\n<thead>\n<tr>\n<th> A table </th>\n<th> must have </th>\n<th> columns </th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td> and rows. </td>\n<td> which may have an arbitrary amount of content </td>\n<td> </td>\n</tr>\n</tbody>\n</table>\n</p>\n<p id=\"footnote-1\">\n<a href=\"#footnote-anchor-1\">[1]</a>\n With footnotes!\n</p>\n<p id=\"footnote-2\">\n<a href=\"#footnote-anchor-2\">[2]</a>\n Pseudocode.\n</p>",
+		},
+		{
+			name:   "an unordered list may contain italics tags, bold tags, and inline code blocks",
+			input:  "For example:\n\n- paragraphs[^1]\n- \"0 < 1\"\n- \"2 > 1\"\n- **and**\n- ***headings***\n- `Code blocks`",
+			output: "For example:\n<p>\n<ul>\n<li> paragraphs<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a></li>\n<li> &quot;0 &lt; 1&quot;</li>\n<li> &quot;2 &gt; 1&quot;</li>\n<li> <b>and</b></li>\n<li> <i><b>headings</b></i></li>\n<li> <code>Code blocks</code></li>\n</ul>\n</p>",
+		},
+		{
+			name:   "paragraph tags should be added correctly after an image is added",
+			input:  "# Introduction\n\n![[image_name.png]]\n\nFor example:",
+			output: "<h1 id=\"introduction\"> Introduction</h1>\n<p>\n<figure class=\"image\">\n<img src=\"/directory_name/image_name.png\">\n</figure>\n</p>\n<p>\nFor example:\n</p>",
+		},
+		{
+			name:   "paragraph tags should be added correctly after an header in text",
+			input:  "# Introduction\n\n## A Small File\n\nThis is a *small* file.",
+			output: "<h1 id=\"introduction\"> Introduction</h1>\n<p>\n<h2 id=\"a-small-file\"> A Small File</h2>\n</p>\n<p>\nThis is a <i>small</i> file.\n</p>",
+		},
+		{
+			name:   "carriage returns should be removed",
+			input:  "Line one.\r\n\r\nLine two.\r\n",
+			output: "Line one.\n<p>\nLine two.\n</p>",
+		},
+		// Below are some additional tests for optional extensions.
+		//{
+		//	name:   "paragraph tags should be added correctly after an h2 title",
+		//	input:  "# Introduction\n\n## A Small File\n\nThis is a *small* file.",
+		//	output: "<h1 id=\"introduction\"> Introduction</h1>\n<p>\n</h2> A Small File</h2>\n</p>\n<p>\nThis is a <i>small</i> file.\n</p>",
+		//},
+		//{
+		//	name:   "a table may have footnotes in it and should replace them correctly",
+		//	input:  "| col name one | col name two |\n|-|-|\n| first row contents one[^1] | first row[^2] contents two |\n| second row contents[^3] one | second row contents two[^4] |",
+		//	output: "<table class=\"table table-hover\">\n<thead>\n<tr>\n<th scope=\"col\"> col name one </th>\n<th scope=\"col\"> col name two </th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td> first row contents one<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a> </td>\n<td> first row<a id=\"footnote-anchor-2\" href=\"#footnote-2\">[2]</a> contents two </td>\n</tr>\n<tr>\n<td> second row contents<a id=\"footnote-anchor-3\" href=\"#footnote-3\">[3]</a> one </td>\n<td> second row contents two<a id=\"footnote-anchor-4\" href=\"#footnote-4\">[4]</a> </td>\n</tr>\n</tbody>\n</table>",
+		//},
+	}
+
+	c := New(DefaultOptions())
+
+	for i, tst := range testCases {
+		sb := strings.Builder{}
+		err := c.Convert(strings.NewReader(tst.input), &sb)
+		if err != nil {
+			t.Errorf("TestConvert test number: %d \nTest name: %s \nunexpected error: %v", i, tst.name, err)
+			continue
+		}
+		res := sb.String()
+		if res != tst.output {
+			t.Errorf(
+				"TestConvert test number: %d \nTest name: %s \nexpected: \n%s \nbut got: \n%s",
+				i, tst.name, tst.output, res,
+			)
+		}
+	}
+}
+
+func TestConvertWithOptions(t *testing.T) {
+	testCases := []struct {
+		name   string
+		opts   Options
+		input  string
+		output string
+	}{
+		{
+			name:   "empty string options should fall back to the defaults",
+			opts:   Options{Images: true},
+			input:  "![[image_name.png]]",
+			output: "<figure class=\"image\">\n<img src=\"/directory_name/image_name.png\">\n</figure>",
+		},
+		{
+			name:   "the image directory and figure class should be taken from the options",
+			opts:   Options{ImageDirectory: "/img", FigureClass: "photo", Images: true},
+			input:  "![[image_name.png]]",
+			output: "<figure class=\"photo\">\n<img src=\"/img/image_name.png\">\n</figure>",
+		},
+		{
+			name:   "the table class should be taken from the options",
+			opts:   Options{TableClass: "plain", Tables: true},
+			input:  "| Table | Head |",
+			output: "<table class=\"plain\">\n<thead>\n<tr>\n<th> Table </th>\n<th> Head </th>\n</tr>\n</thead>\n<tbody>\n</tbody>\n</table>",
+		},
+		{
+			name:   "callout classes should be taken from the options",
+			opts:   Options{Callouts: true, CalloutClasses: map[string]string{"note": "admonition"}},
+			input:  "> [!note] A note",
+			output: "<div class=\"admonition\">\n<div class=\"callout-title\">A note</div>\n</div>",
+		},
+		{
+			name:   "strikethrough, highlights, superscripts and subscripts should be kept as-is when disabled",
+			opts:   Options{},
+			input:  "~~struck~~ ==highlighted== x^2^ H~2~O",
+			output: "~~struck~~ ==highlighted== x^2^ H~2~O",
+		},
+		{
+			name:   "a double tilde should be two subscript tildes when strikethrough is disabled",
+			opts:   Options{Subscripts: true},
+			input:  "~~struck~~ H~2~O",
+			output: "~~struck~~ H<sub>2</sub>O",
+		},
+		{
+			name:   "character references should be escaped when entities are disabled",
+			opts:   Options{},
+			input:  "&copy; [a](/x?a=1&amp;b=2)",
+			output: "&amp;copy; <a href=\"/x?a=1&amp;amp;b=2\">a</a>",
+		},
+		{
+			name:   "classes taken from the options should be escaped",
+			opts:   Options{FigureClass: "a\"b&c", Images: true},
+			input:  "![[image name\".png]]",
+			output: "<figure class=\"a&quot;b&amp;c\">\n<img src=\"/directory_name/image%20name%22.png\">\n</figure>",
+		},
+		{
+			name:   "full typography should turn straight quotes into curly quotes by context",
+			opts:   Options{Typography: TypographyFull, Footnotes: true},
+			input:  "\"Hello,\" she said. 'It's the '90s' -- \"*really*\"[^1]\n\n[^1]: \"Quoted\"...",
+			output: "“Hello,” she said. ‘It’s the ’90s’ – “<i>really</i>”<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a>\n\n<p id=\"footnote-1\">\n<a href=\"#footnote-anchor-1\">[1]</a>\n “Quoted”…\n</p>",
+		},
+		{
+			name:   "full typography should use the quotes given in the options",
+			opts:   Options{Typography: TypographyFull, Quotes: localeQuotes["de"]},
+			input:  "\"Hallo 'Welt'\" und \"'Mond'\"",
+			output: "„Hallo ‚Welt‘“ und „‚Mond‘“",
+		},
+		{
+			name:   "french quotes should be set off by no-break spaces",
+			opts:   Options{Typography: TypographyFull, Quotes: localeQuotes["fr"]},
+			input:  "\"Bonjour\"",
+			output: "«\u00a0Bonjour\u00a0»",
+		},
+		{
+			name:   "dashes, ellipses and quotes should be kept as-is when typography is off",
+			opts:   Options{},
+			input:  "a -- b --- c... \"d\"",
+			output: "a -- b --- c... &quot;d&quot;",
+		},
+		{
+			name:   "callouts should be blockquotes when disabled",
+			opts:   Options{},
+			input:  "> [!note] A note",
+			output: "<blockquote>\n[!note] A note\n</blockquote>",
+		},
+		{
+			name:   "wikilinks to notes the resolver does not know should be unresolved",
+			opts:   Options{Wikilinks: true, NoteResolver: IndexResolver{"Known": "/known/"}},
+			input:  "[[known]] and [[Unknown#Heading]]",
+			output: "<a class=\"wikilink\" href=\"/known/\">known</a> and <a class=\"wikilink wikilink-unresolved\">Unknown &gt; Heading</a>",
+		},
+		{
+			name:   "wikilinks should be resolved by the resolver in the options",
+			opts:   Options{Wikilinks: true, NoteResolver: SlugResolver{Prefix: "/notes/", Extension: "/"}},
+			input:  "[[My Note|*mine*]]",
+			output: "<a class=\"wikilink\" href=\"/notes/my-note/\"><i>mine</i></a>",
+		},
+		{
+			name:   "disabled features should be written as plain text",
+			opts:   Options{},
+			input:  "A note[^1] | not a table | and no ![[image_name.png]]",
+			output: "A note[^1] | not a table | and no ![[image_name.png]]",
+		},
+	}
+
+	for i, tst := range testCases {
+		sb := strings.Builder{}
+		err := New(tst.opts).Convert(strings.NewReader(tst.input), &sb)
+		if err != nil {
+			t.Errorf("TestConvertWithOptions test number: %d \nTest name: %s \nunexpected error: %v", i, tst.name, err)
+			continue
+		}
+		if sb.String() != tst.output {
+			t.Errorf(
+				"TestConvertWithOptions test number: %d \nTest name: %s \nexpected: \n%s \nbut got: \n%s",
+				i, tst.name, tst.output, sb.String(),
+			)
+		}
+	}
+}
+
+func TestConvertConcurrently(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:   "footnotes should be numbered from one in every document",
+			input:  "A footnote[^2] and another.[^1]\n\n[^1]: One.\n[^2]: Two.",
+			output: "A footnote<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a> and another.<a id=\"footnote-anchor-2\" href=\"#footnote-2\">[2]</a>\n\n<p id=\"footnote-2\">\n<a href=\"#footnote-anchor-2\">[2]</a>\n One.\n</p>\n<p id=\"footnote-1\">\n<a href=\"#footnote-anchor-1\">[1]</a>\n Two.\n</p>",
+		},
+		{
+			name:   "footnote numbers should not carry over from other documents",
+			input:  "Only one.[^1]\n\n[^1]: One.",
+			output: "Only one.<a id=\"footnote-anchor-1\" href=\"#footnote-1\">[1]</a>\n\n<p id=\"footnote-1\">\n<a href=\"#footnote-anchor-1\">[1]</a>\n One.\n</p>",
+		},
+		{
+			name:   "images should use the converter's image directory",
+			input:  "# Heading\n\n![[image_name.png]]",
+			output: "<h1 id=\"heading\"> Heading</h1>\n<p>\n<figure class=\"image\">\n<img src=\"/directory_name/image_name.png\">\n</figure>\n</p>",
+		},
+	}
+
+	c := New(DefaultOptions())
+	var wg sync.WaitGroup
+	errs := make(chan string, 50*len(testCases))
+
+	for range 50 {
+		for _, tst := range testCases {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				sb := strings.Builder{}
+				err := c.Convert(strings.NewReader(tst.input), &sb)
+				if err != nil {
+					errs <- fmt.Sprintf("Test name: %s \nunexpected error: %v", tst.name, err)
+					return
+				}
+				if sb.String() != tst.output {
+					errs <- fmt.Sprintf("Test name: %s \nexpected: \n%s \nbut got: \n%s", tst.name, tst.output, sb.String())
+				}
+			}()
+		}
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for e := range errs {
+		t.Errorf("TestConvertConcurrently %s", e)
+	}
+}
+
+func TestConvertReturnsParseErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		position Position
+	}{
+		{
+			name:     "a footnote reference which is not a number should return an error",
+			input:    "A footnote.[^note] More text.",
+			position: Position{Line: 1, Column: 12},
+		},
+		{
+			name:     "a footnote definition which is not a number should return an error at its line",
+			input:    "A footnote.[^1]\n\n[^note]: The note.",
+			position: Position{Line: 3, Column: 1},
+		},
+		{
+			name:     "a footnote in a list should report the column of its '['",
+			input:    "# Title\n\n- item one\n- item[^x] two",
+			position: Position{Line: 4, Column: 7},
+		},
+		{
+			name:     "a footnote in a nested list should report its column",
+			input:    "- item\n  - nested[^x]",
+			position: Position{Line: 2, Column: 11},
+		},
+		{
+			name:     "a footnote after front matter should report its line",
+			input:    "---\ntitle: Post\n---\nA note[^x]",
+			position: Position{Line: 4, Column: 7},
+		},
+		{
+			name:     "a footnote in a blockquote should report its column",
+			input:    "> A note[^x]",
+			position: Position{Line: 1, Column: 9},
+		},
+		{
+			name:     "a footnote in the continued text of a list item should report its column",
+			input:    "- item\n  more[^x]",
+			position: Position{Line: 2, Column: 7},
+		},
+	}
+
+	c := New(DefaultOptions())
+
+	for i, tst := range testCases {
+		sb := strings.Builder{}
+		err := c.Convert(strings.NewReader(tst.input), &sb)
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("TestConvertReturnsParseErrors test number: %d \nTest name: %s \nexpected a *ParseError but got: %v", i, tst.name, err)
+			continue
+		}
+		if parseErr.Position != tst.position {
+			t.Errorf(
+				"TestConvertReturnsParseErrors test number: %d \nTest name: %s \nexpected position: %s \nbut got: %s",
+				i, tst.name, tst.position, parseErr.Position,
+			)
+		}
+	}
+}
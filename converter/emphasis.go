@@ -0,0 +1,240 @@
+package converter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// delimiter is a run of '*' or '_' which may open or close emphasis,
+// following the CommonMark rules. Until it is matched, the run is kept as
+// the Text node text.
+type delimiter struct {
+	text     *Text
+	char     rune
+	count    int // the number of characters not yet matched
+	length   int // the length of the whole run
+	canOpen  bool
+	canClose bool
+
+	// While emphasis is processed, the delimiters and the nodes are each
+	// kept in a linked list, so that matches are found and replaced
+	// without searching or copying the rest of the paragraph.
+	elem       *inline
+	prev, next *delimiter
+}
+
+// inline is an element of the linked list of nodes processEmphasis works on.
+type inline struct {
+	node       Node
+	prev, next *inline
+}
+
+// unlink removes e from its list.
+func (e *inline) unlink() {
+	e.prev.next, e.next.prev = e.next, e.prev
+}
+
+// delimiterRun returns the delimiter for the run of '*' or '_' starting at
+// src[i]. The start and end of src[from:to] count as spaces.
+func (ip *inlineParser) delimiterRun(i, from, to int) *delimiter {
+	char := ip.src[i]
+	n := ip.runLength(i, to, char)
+
+	before, after := ' ', ' '
+	if i > from {
+		before = ip.src[i-1]
+	}
+	if i+n < to {
+		after = ip.src[i+n]
+	}
+
+	// A left-flanking run is not followed by a space, nor by punctuation
+	// unless it also follows a space or punctuation; right-flanking is the
+	// same the other way around.
+	left := !unicode.IsSpace(after) && (!isPunctuation(after) || unicode.IsSpace(before) || isPunctuation(before))
+	right := !unicode.IsSpace(before) && (!isPunctuation(before) || unicode.IsSpace(after) || isPunctuation(after))
+
+	d := &delimiter{
+		text:     &Text{Position: ip.position(i), Value: string(ip.src[i : i+n])},
+		char:     char,
+		count:    n,
+		length:   n,
+		canOpen:  left,
+		canClose: right,
+	}
+	// '_' does not open or close emphasis within a word, as in snake_case.
+	if char == '_' {
+		d.canOpen = left && (!right || isPunctuation(before))
+		d.canClose = right && (!left || isPunctuation(after))
+	}
+
+	return d
+}
+
+func isPunctuation(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// matches reports whether the opener d can be closed by closer. When either
+// run could both open and close, their lengths may not add up to a multiple
+// of three unless both are, so that "*a**b*" is not "<i>a</i><i>b</i>".
+func (d *delimiter) matches(closer *delimiter) bool {
+	if d.char != closer.char || !d.canOpen {
+		return false
+	}
+	if (d.canClose || closer.canOpen) && (d.length+closer.length)%3 == 0 {
+		return d.length%3 == 0 && closer.length%3 == 0
+	}
+
+	return true
+}
+
+// processEmphasis turns the text between matching delimiters in nodes into
+// Emphasis and Strong nodes. Each closer is matched with the nearest opener
+// before it, and delimiters which match nothing are left as text.
+func processEmphasis(nodes []Node, delims []*delimiter) []Node {
+	if len(delims) == 0 {
+		return mergeText(nodes)
+	}
+
+	byText := make(map[*Text]*delimiter, len(delims))
+	for i, d := range delims {
+		byText[d.text] = d
+		if i > 0 {
+			d.prev, delims[i-1].next = delims[i-1], d
+		}
+	}
+
+	// head and tail are sentinels, so that no element is at either end.
+	head, tail := &inline{}, &inline{}
+	last := head
+	for _, n := range nodes {
+		e := &inline{node: n, prev: last}
+		last.next = e
+		last = e
+		if text, ok := n.(*Text); ok && byText[text] != nil {
+			byText[text].elem = e
+		}
+	}
+	last.next, tail.prev = tail, last
+
+	// bottoms records, for each kind of closer, the delimiter below which
+	// no opener was found for it, so that it is not searched again.
+	type closerKind struct {
+		char    rune
+		canOpen bool
+		mod     int
+	}
+	bottoms := map[closerKind]*delimiter{}
+
+	removeDelimiter := func(d *delimiter) {
+		if d.prev != nil {
+			d.prev.next = d.next
+		}
+		if d.next != nil {
+			d.next.prev = d.prev
+		}
+	}
+
+	for closer := delims[0]; closer != nil; {
+		if !closer.canClose {
+			closer = closer.next
+			continue
+		}
+
+		kind := closerKind{closer.char, closer.canOpen, closer.length % 3}
+		opener := closer.prev
+		for opener != nil && opener != bottoms[kind] && !opener.matches(closer) {
+			opener = opener.prev
+		}
+		if opener == nil || opener == bottoms[kind] {
+			bottoms[kind] = closer.prev
+			next := closer.next
+			if !closer.canOpen {
+				removeDelimiter(closer)
+			}
+			closer = next
+			continue
+		}
+
+		use := 1
+		if opener.count >= 2 && closer.count >= 2 {
+			use = 2
+		}
+		opener.count -= use
+		closer.count -= use
+
+		// The characters used are the last of the opener and the first of
+		// the closer.
+		pos := opener.text.Position
+		pos.Column += opener.count
+		var children []Node
+		for e := opener.elem.next; e != closer.elem; e = e.next {
+			children = append(children, e.node)
+		}
+		children = mergeText(children)
+		var node Node = &Emphasis{Position: pos, Children: children}
+		if use == 2 {
+			node = &Strong{Position: pos, Children: children}
+		}
+		e := &inline{node: node, prev: opener.elem, next: closer.elem}
+		opener.elem.next, closer.elem.prev = e, e
+
+		opener.text.Value = strings.Repeat(string(opener.char), opener.count)
+		closer.text.Value = strings.Repeat(string(closer.char), closer.count)
+		closer.text.Column += use
+
+		// Delimiters between the two can no longer match anything.
+		opener.next, closer.prev = closer, opener
+		if opener.count == 0 {
+			opener.elem.unlink()
+			removeDelimiter(opener)
+		}
+		if closer.count == 0 {
+			closer.elem.unlink()
+			removeDelimiter(closer)
+			closer = closer.next
+		}
+	}
+
+	var result []Node
+	for e := head.next; e != tail; e = e.next {
+		result = append(result, e.node)
+	}
+
+	return mergeText(result)
+}
+
+// mergeText joins Text nodes next to each other, such as the text either
+// side of an unmatched delimiter, and drops empty ones.
+func mergeText(nodes []Node) []Node {
+	var merged []Node
+	for i := 0; i < len(nodes); {
+		text, ok := nodes[i].(*Text)
+		if !ok {
+			merged = append(merged, nodes[i])
+			i++
+			continue
+		}
+
+		sb := strings.Builder{}
+		j := i
+		for ; j < len(nodes); j++ {
+			next, ok := nodes[j].(*Text)
+			if !ok {
+				break
+			}
+			sb.WriteString(next.Value)
+		}
+		switch {
+		case sb.Len() == 0:
+		case j == i+1:
+			merged = append(merged, text)
+		default:
+			merged = append(merged, &Text{Position: text.Position, Value: sb.String()})
+		}
+		i = j
+	}
+
+	return merged
+}
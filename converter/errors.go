@@ -0,0 +1,40 @@
+package converter
+
+import "fmt"
+
+// Position is a line and column in a Markdown document. Both start at 1.
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// ParseError is returned by Convert when a document cannot be read or
+// converted. Position is where in the input the problem was found.
+type ParseError struct {
+	Position
+	Msg string
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Position, e.Msg, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Position, e.Msg)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func errorAt(pos Position, err error, format string, args ...any) error {
+	return &ParseError{
+		Position: pos,
+		Msg:      fmt.Sprintf(format, args...),
+		Err:      err,
+	}
+}
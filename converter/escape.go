@@ -0,0 +1,171 @@
+package converter
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// htmlEntityMap gives the entity written in place of each special
+// character in text.
+var htmlEntityMap = map[rune]string{
+	'&':  "&amp;",
+	'\'': "&apos;",
+	'<':  "&lt;",
+	'>':  "&gt;",
+	'"':  "&quot;",
+}
+
+// attributeEntityMap gives the entity written in place of each special
+// character in an attribute value.
+var attributeEntityMap = map[rune]string{
+	'&':  "&amp;",
+	'<':  "&lt;",
+	'>':  "&gt;",
+	'"':  "&quot;",
+	'\'': "&#39;",
+}
+
+// escapeText escapes s to be written as HTML text. If references is set,
+// character references in s, such as &copy;, are kept as they are rather
+// than having their '&' escaped.
+func escapeText(s string, references bool) string {
+	return escape(s, htmlEntityMap, references)
+}
+
+// escapeAttribute escapes s to be written between the double quotes of an
+// attribute, keeping character references if references is set.
+func escapeAttribute(s string, references bool) string {
+	return escape(s, attributeEntityMap, references)
+}
+
+// escapeURL percent-encodes the characters of s which may not appear in a
+// URL, leaving any existing percent-encoding alone, and then escapes it as
+// an attribute. A URL with a scheme which runs code, such as javascript:,
+// is replaced by "#". So is a data: URL, unless image is set and it is a
+// data:image/ one.
+func escapeURL(s string, references, image bool) string {
+	if !isSafeURL(s, references, image) {
+		return "#"
+	}
+
+	sb := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; {
+		case b == '%' && (i+2 >= len(s) || !isHexDigit(s[i+1]) || !isHexDigit(s[i+2])):
+			sb.WriteString("%25")
+		case isURLByte(b):
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+
+	return escapeAttribute(sb.String(), references)
+}
+
+// isSafeURL reports whether s does not have a javascript:, vbscript: or
+// data: scheme, or else is an image's data:image/ URL. As browsers do, the
+// scheme is read ignoring case, whitespace and control characters, and after
+// decoding any character references which are kept.
+func isSafeURL(s string, references, image bool) bool {
+	if references {
+		s = html.UnescapeString(s)
+	}
+	scheme := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
+
+	switch {
+	case strings.HasPrefix(scheme, "javascript:"), strings.HasPrefix(scheme, "vbscript:"):
+		return false
+	case strings.HasPrefix(scheme, "data:"):
+		return image && strings.HasPrefix(scheme, "data:image/")
+	}
+
+	return true
+}
+
+func isHexDigit(b byte) bool {
+	return '0' <= b && b <= '9' || 'a' <= b && b <= 'f' || 'A' <= b && b <= 'F'
+}
+
+func isURLByte(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+
+	return strings.IndexByte("-._~:/?#[]@!$&'()*+,;=%", b) != -1
+}
+
+func escape(s string, entities map[rune]string, references bool) string {
+	sb := strings.Builder{}
+	for i := 0; i < len(s); {
+		if n := characterReference(s[i:]); references && n > 0 {
+			sb.WriteString(s[i : i+n])
+			i += n
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if entity, ok := entities[r]; ok {
+			sb.WriteString(entity)
+		} else {
+			sb.WriteRune(r)
+		}
+		i += size
+	}
+
+	return sb.String()
+}
+
+// characterReference returns the length of the character reference at the
+// start of s: a named one such as "&copy;", or a numeric one such as
+// "&#169;" or "&#xA9;". It returns 0 if s does not start with one.
+func characterReference(s string) int {
+	if !strings.HasPrefix(s, "&") {
+		return 0
+	}
+	end := strings.IndexByte(s, ';')
+	if end < 2 || end > 32 {
+		return 0
+	}
+	name := s[1:end]
+
+	if digits, ok := strings.CutPrefix(name, "#"); ok {
+		max, isDigit := 7, func(c byte) bool { return '0' <= c && c <= '9' }
+		if hex, ok := strings.CutPrefix(strings.ToLower(digits), "x"); ok {
+			digits, max = hex, 6
+			isDigit = func(c byte) bool { return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' }
+		}
+		if digits == "" || len(digits) > max {
+			return 0
+		}
+		for i := 0; i < len(digits); i++ {
+			if !isDigit(digits[i]) {
+				return 0
+			}
+		}
+		return end + 1
+	}
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return 0
+		}
+	}
+	// html only knows the names HTML does. It also unescapes the start of
+	// a longer name, as in "&notit;", which is not a reference.
+	if u := html.UnescapeString(s[:end+1]); u == s[:end+1] || strings.HasSuffix(u, ";") {
+		return 0
+	}
+
+	return end + 1
+}
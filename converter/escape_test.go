@@ -0,0 +1,59 @@
+package converter
+
+import "testing"
+
+func TestCharacterReference(t *testing.T) {
+	testCases := []struct {
+		input  string
+		length int
+	}{
+		{"&copy; 2024", 6},
+		{"&#169;", 6},
+		{"&#xA9;", 6},
+		{"&#XA9;", 6},
+		{"&amp;amp;", 5},
+		{"&notit;", 0},
+		{"&madeup;", 0},
+		{"&copy", 0},
+		{"&#;", 0},
+		{"&#12345678;", 0},
+		{"&#xG;", 0},
+		{"& copy;", 0},
+		{"AT&T", 0},
+	}
+
+	for _, tst := range testCases {
+		if res := characterReference(tst.input); res != tst.length {
+			t.Errorf("TestCharacterReference expected %d for %q but got %d", tst.length, tst.input, res)
+		}
+	}
+}
+
+func TestEscapeURL(t *testing.T) {
+	testCases := []struct {
+		input  string
+		image  bool
+		output string
+	}{
+		{"/a%20b", false, "/a%20b"},
+		{"/100%", false, "/100%25"},
+		{"/a%2", false, "/a%252"},
+		{"/a%zzb", false, "/a%25zzb"},
+		{"javascript:alert(1)", false, "#"},
+		{"JavaScript:alert(1)", false, "#"},
+		{" java\tscript:alert(1)", false, "#"},
+		{"&#106;avascript:alert(1)", false, "#"},
+		{"vbscript:msgbox", false, "#"},
+		{"data:text/html,x", false, "#"},
+		{"data:text/html,x", true, "#"},
+		{"data:image/png;base64,iVBO", false, "#"},
+		{"data:image/png;base64,iVBO", true, "data:image/png;base64,iVBO"},
+		{"https://example.com/javascript:", false, "https://example.com/javascript:"},
+	}
+
+	for _, tst := range testCases {
+		if res := escapeURL(tst.input, true, tst.image); res != tst.output {
+			t.Errorf("TestEscapeURL expected %q for %q but got %q", tst.output, tst.input, res)
+		}
+	}
+}
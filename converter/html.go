@@ -0,0 +1,430 @@
+package converter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// HTMLRenderer is the Renderer used when Options.Renderer is nil. Embed it
+// in another type to change how some kinds of node are written.
+type HTMLRenderer struct{}
+
+// DocumentStart writes nothing: the output is a fragment to be placed in a
+// page template.
+func (HTMLRenderer) DocumentStart(ctx *RenderContext, n *Document) error {
+	return ctx.Err()
+}
+
+func (HTMLRenderer) DocumentEnd(ctx *RenderContext, n *Document) error {
+	return ctx.Err()
+}
+
+// Paragraph wraps n in <p> tags, unless it is the first paragraph of the
+// document or it starts with a footnote. Footnotes are instead separated from
+// the paragraph before them by a blank line, unless that paragraph ended in a
+// block of its own.
+func (HTMLRenderer) Paragraph(ctx *RenderContext, n *Paragraph) error {
+	previous, _ := ctx.PreviousSibling().(*Paragraph)
+	bare := ctx.PreviousSibling() == nil || startsWithFootnote(n)
+
+	if bare && previous != nil && !endsWithBlock(previous) {
+		ctx.WriteString("\n")
+	}
+	if !bare {
+		ctx.WriteString("<p>\n")
+	}
+	ctx.RenderChildren(n.Children, "\n")
+	if !bare {
+		ctx.WriteString("\n</p>")
+	}
+
+	return ctx.Err()
+}
+
+func startsWithFootnote(para *Paragraph) bool {
+	if len(para.Children) == 0 {
+		return false
+	}
+	switch n := para.Children[0].(type) {
+	case *FootnoteDefinition:
+		return true
+	case *TextBlock:
+		if len(n.Children) == 0 {
+			return false
+		}
+		_, ok := n.Children[0].(*FootnoteReference)
+		return ok
+	}
+
+	return false
+}
+
+func endsWithBlock(para *Paragraph) bool {
+	if len(para.Children) == 0 {
+		return false
+	}
+	switch n := para.Children[len(para.Children)-1].(type) {
+	case *Heading, *List, *Blockquote, *Callout, *ThematicBreak, *Table:
+		return true
+	case *TextBlock:
+		if len(n.Children) == 0 {
+			return false
+		}
+		_, ok := n.Children[len(n.Children)-1].(*Image)
+		return ok
+	}
+
+	return false
+}
+
+func (HTMLRenderer) Heading(ctx *RenderContext, n *Heading) error {
+	level := strconv.Itoa(n.Level)
+	ctx.WriteString("<h" + level)
+	if n.ID != "" {
+		ctx.WriteString(" id=\"" + ctx.EscapeAttribute(n.ID) + "\"")
+	}
+	ctx.WriteString(">")
+	ctx.RenderChildren(n.Children, "")
+	ctx.WriteString("</h" + level + ">")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) TextBlock(ctx *RenderContext, n *TextBlock) error {
+	return ctx.RenderChildren(n.Children, "")
+}
+
+// List writes an ordered list as <ol>, with a start attribute unless it
+// starts at 1, and any other list as <ul>.
+func (HTMLRenderer) List(ctx *RenderContext, n *List) error {
+	tag := "ul"
+	if n.Ordered {
+		tag = "ol"
+	}
+	ctx.WriteString("<" + tag)
+	if n.Ordered && n.Start != 1 {
+		ctx.WriteString(" start=\"" + strconv.Itoa(n.Start) + "\"")
+	}
+	ctx.WriteString(">\n")
+	for _, item := range n.Items {
+		ctx.Render(item)
+	}
+	ctx.WriteString("</" + tag + ">")
+
+	return ctx.Err()
+}
+
+// ListItem writes the lists and paragraphs nested in n on lines of their
+// own, after its text. A task is given a checkbox which cannot be changed.
+func (HTMLRenderer) ListItem(ctx *RenderContext, n *ListItem) error {
+	switch {
+	case n.Checked:
+		ctx.WriteString("<li class=\"task-list-item\"><input type=\"checkbox\" disabled checked>")
+	case n.Task:
+		ctx.WriteString("<li class=\"task-list-item\"><input type=\"checkbox\" disabled>")
+	default:
+		ctx.WriteString("<li>")
+	}
+	var prev Node
+	for _, child := range n.Children {
+		sep := ""
+		if isBlock(child) {
+			sep = "\n"
+		}
+		ctx.renderChild(child, prev, sep)
+		prev = child
+	}
+	if prev != nil && isBlock(prev) {
+		ctx.WriteString("\n")
+	}
+	ctx.WriteString("</li>\n")
+
+	return ctx.Err()
+}
+
+// isBlock reports whether n is a block, rather than an inline node.
+func isBlock(n Node) bool {
+	switch n.(type) {
+	case *Paragraph, *Heading, *TextBlock, *List, *Blockquote, *Callout, *ThematicBreak, *CodeBlock, *Table, *FootnoteDefinition:
+		return true
+	}
+
+	return false
+}
+
+// Blockquote writes the paragraphs of n like those of a document, so the
+// first is not wrapped in <p> tags.
+func (HTMLRenderer) Blockquote(ctx *RenderContext, n *Blockquote) error {
+	ctx.WriteString("<blockquote>\n")
+	ctx.RenderChildren(n.Children, "\n")
+	ctx.WriteString("\n</blockquote>")
+
+	return ctx.Err()
+}
+
+// Callout writes n as a <div>, or as a <details> if it is foldable, with
+// the class given by Options.CalloutClasses. A callout without a title is
+// titled with its type.
+func (HTMLRenderer) Callout(ctx *RenderContext, n *Callout) error {
+	class, ok := ctx.Options().CalloutClasses[n.Type]
+	if !ok {
+		class = "callout callout-" + n.Type
+	}
+
+	tag, titleTag := "div", "div"
+	open := ""
+	if n.Foldable {
+		tag, titleTag = "details", "summary"
+		if n.Open {
+			open = " open"
+		}
+	}
+
+	ctx.WriteString("<" + tag + " class=\"" + ctx.EscapeAttribute(class) + "\"" + open + ">\n")
+	ctx.WriteString("<" + titleTag + " class=\"callout-title\">")
+	if len(n.Title) > 0 {
+		ctx.RenderChildren(n.Title, "")
+	} else {
+		ctx.WriteEscaped(strings.ToUpper(n.Type[:1]) + n.Type[1:])
+	}
+	ctx.WriteString("</" + titleTag + ">\n")
+	if len(n.Children) > 0 {
+		ctx.WriteString("<div class=\"callout-content\">\n")
+		ctx.RenderChildren(n.Children, "\n")
+		ctx.WriteString("\n</div>\n")
+	}
+	ctx.WriteString("</" + tag + ">")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) ThematicBreak(ctx *RenderContext, n *ThematicBreak) error {
+	_, err := ctx.WriteString("<hr>")
+	return err
+}
+
+func (HTMLRenderer) CodeBlock(ctx *RenderContext, n *CodeBlock) error {
+	ctx.WriteString("<pre><code>\n")
+	for _, line := range n.Lines {
+		ctx.WriteString(escapeText(line, false))
+		ctx.WriteString("\n")
+	}
+	ctx.WriteString("</code></pre>")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) Table(ctx *RenderContext, n *Table) error {
+	ctx.WriteString("<table class=\"" + ctx.EscapeAttribute(ctx.Options().TableClass) + "\">\n")
+	ctx.WriteString("<thead>\n")
+	ctx.Render(n.Header)
+	ctx.WriteString("</thead>\n")
+	ctx.WriteString("<tbody>\n")
+	for _, row := range n.Rows {
+		ctx.Render(row)
+	}
+	ctx.WriteString("</tbody>\n</table>")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) TableRow(ctx *RenderContext, n *TableRow) error {
+	ctx.WriteString("<tr>\n")
+	for _, cell := range n.Cells {
+		ctx.Render(cell)
+	}
+	ctx.WriteString("</tr>\n")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) TableCell(ctx *RenderContext, n *TableCell) error {
+	tag := "td"
+	if n.Header {
+		tag = "th"
+	}
+	ctx.WriteString("<" + tag + ">")
+	ctx.RenderChildren(n.Children, "")
+	ctx.WriteString("</" + tag + ">\n")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) FootnoteDefinition(ctx *RenderContext, n *FootnoteDefinition) error {
+	number := strconv.Itoa(n.Number)
+	ctx.WriteString("<p id=\"footnote-" + number + "\">\n")
+	ctx.WriteString("<a href=\"#footnote-anchor-" + number + "\">[" + number + "]</a>\n")
+	ctx.RenderChildren(n.Children, "")
+	ctx.WriteString("\n</p>")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) Text(ctx *RenderContext, n *Text) error {
+	_, err := ctx.WriteEscaped(n.Value)
+	return err
+}
+
+func (HTMLRenderer) SoftBreak(ctx *RenderContext, n *SoftBreak) error {
+	_, err := ctx.WriteString("\n")
+	return err
+}
+
+func (HTMLRenderer) Emphasis(ctx *RenderContext, n *Emphasis) error {
+	ctx.WriteString("<i>")
+	ctx.RenderChildren(n.Children, "")
+	ctx.WriteString("</i>")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) Strong(ctx *RenderContext, n *Strong) error {
+	ctx.WriteString("<b>")
+	ctx.RenderChildren(n.Children, "")
+	ctx.WriteString("</b>")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) Strikethrough(ctx *RenderContext, n *Strikethrough) error {
+	ctx.WriteString("<del>")
+	ctx.RenderChildren(n.Children, "")
+	ctx.WriteString("</del>")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) Highlight(ctx *RenderContext, n *Highlight) error {
+	ctx.WriteString("<mark>")
+	ctx.RenderChildren(n.Children, "")
+	ctx.WriteString("</mark>")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) Superscript(ctx *RenderContext, n *Superscript) error {
+	ctx.WriteString("<sup>")
+	ctx.RenderChildren(n.Children, "")
+	ctx.WriteString("</sup>")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) Subscript(ctx *RenderContext, n *Subscript) error {
+	ctx.WriteString("<sub>")
+	ctx.RenderChildren(n.Children, "")
+	ctx.WriteString("</sub>")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) CodeSpan(ctx *RenderContext, n *CodeSpan) error {
+	ctx.WriteString("<code>")
+	ctx.WriteString(escapeText(n.Value, false))
+	ctx.WriteString("</code>")
+
+	return ctx.Err()
+}
+
+func (HTMLRenderer) FootnoteReference(ctx *RenderContext, n *FootnoteReference) error {
+	number := strconv.Itoa(n.Number)
+	_, err := ctx.WriteString("<a id=\"footnote-anchor-" + number + "\" href=\"#footnote-" + number + "\">[" + number + "]</a>")
+	return err
+}
+
+// Link writes n as an <a> tag. A reference link which was never defined is
+// written as the text it was parsed from.
+func (HTMLRenderer) Link(ctx *RenderContext, n *Link) error {
+	if n.Undefined {
+		ctx.WriteString("[")
+		ctx.RenderChildren(n.Children, "")
+		ctx.WriteString("]")
+		ctx.WriteEscaped(n.Source)
+		return ctx.Err()
+	}
+
+	ctx.WriteString("<a href=\"" + ctx.EscapeURL(n.Destination) + "\"")
+	if n.Title != "" {
+		ctx.WriteString(" title=\"" + ctx.EscapeAttribute(n.Title) + "\"")
+	}
+	ctx.WriteString(">")
+	ctx.RenderChildren(n.Children, "")
+	ctx.WriteString("</a>")
+
+	return ctx.Err()
+}
+
+// WikiLink writes n as an <a> tag with the class "wikilink". A link to a note
+// which could not be resolved has no href, and the class
+// "wikilink wikilink-unresolved".
+func (HTMLRenderer) WikiLink(ctx *RenderContext, n *WikiLink) error {
+	if n.Unresolved {
+		ctx.WriteString("<a class=\"wikilink wikilink-unresolved\">")
+	} else {
+		ctx.WriteString("<a class=\"wikilink\" href=\"" + ctx.EscapeURL(n.Destination) + "\">")
+	}
+	ctx.RenderChildren(n.Children, "")
+	ctx.WriteString("</a>")
+
+	return ctx.Err()
+}
+
+// Image writes n in a <figure>, followed by its caption, if any. Its alt,
+// title, width and height attributes are only written if it has them.
+func (HTMLRenderer) Image(ctx *RenderContext, n *Image) error {
+	opts := ctx.Options()
+	ctx.WriteString("<figure class=\"" + ctx.EscapeAttribute(opts.FigureClass) + "\">\n")
+	ctx.WriteString("<img src=\"" + ctx.EscapeImageURL(imageSource(opts, n.Name)) + "\"")
+	if n.Alt != "" {
+		ctx.WriteString(" alt=\"" + ctx.EscapeAttribute(n.Alt) + "\"")
+	}
+	if n.Title != "" {
+		ctx.WriteString(" title=\"" + ctx.EscapeAttribute(n.Title) + "\"")
+	}
+	if n.Width > 0 {
+		ctx.WriteString(" width=\"" + strconv.Itoa(n.Width) + "\"")
+	}
+	if n.Height > 0 {
+		ctx.WriteString(" height=\"" + strconv.Itoa(n.Height) + "\"")
+	}
+	ctx.WriteString(">\n")
+	if len(n.Caption) > 0 {
+		ctx.WriteString("<figcaption>")
+		ctx.RenderChildren(n.Caption, "")
+		ctx.WriteString("</figcaption>\n")
+	}
+	ctx.WriteString("</figure>")
+
+	return ctx.Err()
+}
+
+// imageSource returns the URL of the image at path: path itself if it is an
+// absolute URL, such as https://example.com/image.png or /image.png, and
+// otherwise path within the image directory.
+func imageSource(opts Options, path string) string {
+	if isAbsoluteURL(path) {
+		return path
+	}
+
+	return opts.ImageDirectory + "/" + path
+}
+
+// isAbsoluteURL reports whether s starts with a '/' or a scheme, such as
+// "https:".
+func isAbsoluteURL(s string) bool {
+	if strings.HasPrefix(s, "/") {
+		return true
+	}
+	scheme, _, ok := strings.Cut(s, ":")
+	if !ok || scheme == "" {
+		return false
+	}
+	for i, r := range scheme {
+		isLetter := 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+		if !isLetter && (i == 0 || !('0' <= r && r <= '9' || r == '+' || r == '-' || r == '.')) {
+			return false
+		}
+	}
+
+	return true
+}
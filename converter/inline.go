@@ -0,0 +1,717 @@
+package converter
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// inlineParser turns a run of text into Text, Emphasis, CodeSpan and other
+// inline nodes. src may span several lines; start is the position of src[0],
+// and lineColumn is the column each of the lines after the first starts at.
+type inlineParser struct {
+	p          *parser
+	src        []rune
+	start      Position
+	lineColumn int
+	lineStarts []int // the index in src of the start of each line after the first
+
+	// brackets maps the index of each '[' to the index of its ']', or -1.
+	brackets map[int]int
+	// misses are the searches which found nothing, so that each opener
+	// without a closer does not search to the end of src again.
+	misses map[search]miss
+}
+
+// search is what find looks for: r, before the end of the line if onLine
+// is set, and skipping characters escaped by a backslash if escapes is set.
+// parseDelimited looks for a closing run of exactly run r instead.
+type search struct {
+	r        rune
+	onLine   bool
+	escapes  bool
+	run      int
+	noSpaces bool
+}
+
+// miss is a search of src[from:to] which found nothing before stop, the
+// end of the line or to.
+type miss struct {
+	from, stop, to int
+}
+
+func (p *parser) parseInlines(src string, start Position, lineColumn int) ([]Node, error) {
+	ip := inlineParser{p: p, src: []rune(src), start: start, lineColumn: lineColumn}
+	for i, r := range ip.src {
+		if r == '\n' {
+			ip.lineStarts = append(ip.lineStarts, i+1)
+		}
+	}
+
+	return ip.parse(0, len(ip.src))
+}
+
+// position returns the line and column of src[i].
+func (ip *inlineParser) position(i int) Position {
+	line := sort.SearchInts(ip.lineStarts, i+1)
+	if line == 0 {
+		return Position{Line: ip.start.Line, Column: ip.start.Column + i}
+	}
+
+	return Position{Line: ip.start.Line + line, Column: ip.lineColumn + i - ip.lineStarts[line-1]}
+}
+
+// parse returns the nodes making up src[from:to].
+func (ip *inlineParser) parse(from, to int) ([]Node, error) {
+	var nodes []Node
+	var delims []*delimiter
+	text := strings.Builder{}
+	textStart := from
+
+	addText := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, &Text{Position: ip.position(textStart), Value: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := from; i < to; {
+		if s, next := ip.typography(i, to); next > i {
+			text.WriteString(s)
+			i = next
+			continue
+		}
+
+		var node Node
+		var next int
+		var err error
+
+		switch ip.src[i] {
+		case '\\':
+			if i+1 < to && isEscapable(ip.src[i+1]) {
+				node, next = &Text{Position: ip.position(i), Value: string(ip.src[i+1])}, i+2
+			}
+		case '\n':
+			node, next = &SoftBreak{Position: ip.position(i)}, i+1
+		case '*', '_':
+			d := ip.delimiterRun(i, from, to)
+			delims = append(delims, d)
+			node, next = d.text, i+d.count
+		case '~':
+			node, next, err = ip.parseTilde(i, to)
+		case '=':
+			if ip.p.opts.Highlights {
+				node, next, err = ip.parseDelimited(i, to, 2, false, func(pos Position, children []Node) Node {
+					return &Highlight{Position: pos, Children: children}
+				})
+			}
+		case '^':
+			if ip.p.opts.Superscripts {
+				node, next, err = ip.parseDelimited(i, to, 1, true, func(pos Position, children []Node) Node {
+					return &Superscript{Position: pos, Children: children}
+				})
+			}
+		case '`':
+			node, next = ip.parseCodeSpan(i, to)
+		case '[':
+			node, next, err = ip.parseWikiLink(i, to)
+			if err == nil && next == i {
+				node, next, err = ip.parseFootnoteReference(i, to)
+			}
+			if err == nil && next == i {
+				node, next, err = ip.parseLink(i, to)
+			}
+		case '!':
+			node, next, err = ip.parseImage(i, to)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if next > i {
+			addText()
+			if node != nil {
+				nodes = append(nodes, node)
+			}
+			i = next
+			textStart = i
+			continue
+		}
+
+		text.WriteRune(ip.src[i])
+		i++
+	}
+	addText()
+
+	return processEmphasis(nodes, delims), nil
+}
+
+// isEscapable reports whether r is ASCII punctuation, which a backslash
+// before it turns into a literal character.
+func isEscapable(r rune) bool {
+	return strings.ContainsRune("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", r)
+}
+
+// isEscape reports whether src[j] is a backslash escaping the character
+// after it.
+func isEscape(src []rune, j, to int) bool {
+	return src[j] == '\\' && j+1 < to && isEscapable(src[j+1])
+}
+
+// unescape removes the backslashes escaping characters in s, for the
+// destination and title of a link.
+func unescape(s string) string {
+	src := []rune(s)
+	sb := strings.Builder{}
+	for j := 0; j < len(src); j++ {
+		if isEscape(src, j, len(src)) {
+			j++
+		}
+		sb.WriteRune(src[j])
+	}
+
+	return sb.String()
+}
+
+// runLength returns the number of r in a row starting at src[i].
+func (ip *inlineParser) runLength(i, to int, r rune) int {
+	n := 0
+	for i+n < to && ip.src[i+n] == r {
+		n++
+	}
+
+	return n
+}
+
+// parseTilde parses "~~struck~~" or "~sub~".
+func (ip *inlineParser) parseTilde(i, to int) (Node, int, error) {
+	if ip.p.opts.Strikethrough {
+		node, next, err := ip.parseDelimited(i, to, 2, false, func(pos Position, children []Node) Node {
+			return &Strikethrough{Position: pos, Children: children}
+		})
+		if err != nil || next > i {
+			return node, next, err
+		}
+	}
+	if ip.p.opts.Subscripts {
+		return ip.parseDelimited(i, to, 1, true, func(pos Position, children []Node) Node {
+			return &Subscript{Position: pos, Children: children}
+		})
+	}
+
+	return nil, i, nil
+}
+
+// parseDelimited parses text surrounded by runs of exactly n of the
+// character at src[i], which must not have a space just inside them. If
+// noSpaces is set, the text may not contain spaces at all, so that the '~'
+// of "~5 to ~10" is left as it is. newNode makes the node from its
+// children. Returns next == i if there is no closing run.
+func (ip *inlineParser) parseDelimited(i, to, n int, noSpaces bool, newNode func(Position, []Node) Node) (Node, int, error) {
+	r := ip.src[i]
+	if ip.runLength(i, to, r) != n || i+n == to || isSpace(ip.src[i+n]) {
+		return nil, i, nil
+	}
+	// The end of a longer run, as in "~~~a~~", which was left as text, is
+	// not an opening run either.
+	if i > 0 && ip.src[i-1] == r && (i < 2 || !isEscape(ip.src, i-2, i)) {
+		return nil, i, nil
+	}
+
+	// A search for a closing run which found nothing is not made again
+	// from any of the openers it passed, so that a run of them takes time
+	// in proportion to its length. Only a search to the same end counts,
+	// as a run cut short by an earlier end may close.
+	s := search{r: r, run: n, noSpaces: noSpaces}
+	if m, ok := ip.misses[s]; ok && m.from <= i+n && i+n <= m.stop && to == m.to {
+		return nil, i, nil
+	}
+
+	j := i + n
+	for j < to {
+		if noSpaces && isSpace(ip.src[j]) {
+			break
+		}
+		if isEscape(ip.src, j, to) {
+			j += 2
+			continue
+		}
+		m := ip.runLength(j, to, r)
+		if m == 0 {
+			j++
+			continue
+		}
+		if m != n || isSpace(ip.src[j-1]) {
+			j += m
+			continue
+		}
+
+		children, err := ip.parse(i+n, j)
+		if err != nil {
+			return nil, i, err
+		}
+
+		return newNode(ip.position(i), children), j + n, nil
+	}
+
+	if ip.misses == nil {
+		ip.misses = map[search]miss{}
+	}
+	ip.misses[s] = miss{from: i + n, stop: j, to: to}
+
+	return nil, i, nil
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n'
+}
+
+// parseCodeSpan parses text surrounded by '`'. An empty code span, "“", is
+// dropped.
+func (ip *inlineParser) parseCodeSpan(i, to int) (Node, int) {
+	if i+1 < to && ip.src[i+1] == '`' {
+		return nil, i + 2
+	}
+
+	if j := ip.find(i+1, to, search{r: '`'}); j != -1 {
+		return &CodeSpan{Position: ip.position(i), Value: string(ip.src[i+1 : j])}, j + 1
+	}
+
+	return nil, i
+}
+
+// indexOnLine returns the index of the first r in src[from:to] before the
+// end of the line, or -1 if there is none.
+func (ip *inlineParser) indexOnLine(from, to int, r rune) int {
+	return ip.find(from, to, search{r: r, onLine: true})
+}
+
+// find returns the index of the first s.r in src[from:to], or -1 if there
+// is none. A search within one which found nothing is not made again.
+func (ip *inlineParser) find(from, to int, s search) int {
+	if m, ok := ip.misses[s]; ok && m.from <= from && from <= m.stop && to <= m.to {
+		return -1
+	}
+
+	j := from
+	for ; j < to; j++ {
+		if s.onLine && ip.src[j] == '\n' {
+			break
+		}
+		if s.escapes && isEscape(ip.src, j, to) {
+			j++
+			continue
+		}
+		if ip.src[j] == s.r {
+			return j
+		}
+	}
+
+	if ip.misses == nil {
+		ip.misses = map[search]miss{}
+	}
+	ip.misses[s] = miss{from: from, stop: j, to: to}
+
+	return -1
+}
+
+// parseFootnoteReference parses a "[^n]", numbering it after the references
+// before it.
+func (ip *inlineParser) parseFootnoteReference(i, to int) (Node, int, error) {
+	if !ip.p.opts.Footnotes || i+1 == to || ip.src[i+1] != '^' {
+		return nil, i, nil
+	}
+	end := ip.indexOnLine(i+2, to, ']')
+	if end == -1 {
+		return nil, i, nil
+	}
+	label := string(ip.src[i+2 : end])
+
+	footnoteOriginalNumber, err := strconv.Atoi(label)
+	if err != nil {
+		return nil, i, errorAt(ip.position(i), err, "invalid footnote number %q", label)
+	}
+
+	ip.p.inlineFootnoteNumber++
+	ip.p.footnoteNumberMap[footnoteOriginalNumber] = ip.p.inlineFootnoteNumber
+
+	return &FootnoteReference{
+		Position: ip.position(i),
+		Label:    label,
+		Number:   ip.p.inlineFootnoteNumber,
+	}, end + 1, nil
+}
+
+// parseImage parses an Obsidian embed of the form ![[image_name.png]], or a
+// Markdown image, ![alt text](path "title"). An embed may be followed by a
+// size, ![[image_name.png|300]] or ![[image_name.png|300x200]], and a
+// caption, ![[image_name.png|A caption]], each after a '|'.
+func (ip *inlineParser) parseImage(i, to int) (Node, int, error) {
+	if !ip.p.opts.Images {
+		return nil, i, nil
+	}
+	if !strings.HasPrefix(string(ip.src[i:min(i+3, to)]), "![[") {
+		return ip.parseMarkdownImage(i, to)
+	}
+	end := ip.indexOnLine(i+3, to, ']')
+	if end == -1 || end+1 == to || ip.src[end+1] != ']' {
+		return nil, i, nil
+	}
+
+	image := &Image{Position: ip.position(i)}
+	start := i + 3
+	for j := start; j <= end; j++ {
+		if j < end && ip.src[j] != '|' {
+			continue
+		}
+		if start == i+3 {
+			image.Name = string(ip.src[start:j])
+		} else if !image.setSize(string(ip.src[start:j])) {
+			caption, err := ip.parse(start, j)
+			if err != nil {
+				return nil, i, err
+			}
+			image.Caption = caption
+			image.Alt = plainText(caption)
+		}
+		start = j + 1
+	}
+
+	return image, end + 2, nil
+}
+
+// setSize sets the width and height of image from s, of the form "300" or
+// "300x200", returning false if s is not a size.
+func (image *Image) setSize(s string) bool {
+	width, height, hasHeight := strings.Cut(s, "x")
+	w, err := strconv.Atoi(width)
+	if err != nil || w <= 0 {
+		return false
+	}
+	h := 0
+	if hasHeight {
+		h, err = strconv.Atoi(height)
+		if err != nil || h <= 0 {
+			return false
+		}
+	}
+	image.Width, image.Height = w, h
+
+	return true
+}
+
+// closingBracket returns the index of the ']' matching the '[' at src[i],
+// or -1 if there is none before to. The brackets of the whole of src are
+// matched the first time it is called.
+func (ip *inlineParser) closingBracket(i, to int) int {
+	if ip.brackets == nil {
+		ip.brackets = map[int]int{}
+		var open []int
+		for j := 0; j < len(ip.src); j++ {
+			switch ip.src[j] {
+			case '\\':
+				if isEscape(ip.src, j, len(ip.src)) {
+					j++
+				}
+			case '[':
+				ip.brackets[j] = -1
+				open = append(open, j)
+			case ']':
+				if len(open) > 0 {
+					ip.brackets[open[len(open)-1]] = j
+					open = open[:len(open)-1]
+				}
+			}
+		}
+	}
+
+	if end, ok := ip.brackets[i]; ok && end < to {
+		return end
+	}
+
+	return -1
+}
+
+// parseLink parses an inline link, [text](destination "title"), or a
+// reference link, [text][label], [text][] or [text]. Returns next == i if
+// there is neither.
+func (ip *inlineParser) parseLink(i, to int) (Node, int, error) {
+	end := ip.closingBracket(i, to)
+	if end == -1 {
+		return nil, i, nil
+	}
+
+	link := &Link{Position: ip.position(i)}
+	next := ip.parseInlineLink(link, end+1, to)
+	if next == -1 {
+		next = ip.parseLinkReference(link, i, end, to)
+	}
+	if next == -1 {
+		return nil, i, nil
+	}
+
+	children, err := ip.parse(i+1, end)
+	if err != nil {
+		return nil, i, err
+	}
+	link.Children = children
+
+	if link.Reference != "" {
+		ip.p.defineLink(link)
+	}
+
+	return link, next, nil
+}
+
+// parseInlineLink parses the (destination "title") of an inline link
+// starting at src[j], returning the index after it, or -1 if there is none.
+func (ip *inlineParser) parseInlineLink(link *Link, j, to int) int {
+	if j >= to || ip.src[j] != '(' {
+		return -1
+	}
+
+	k := skipSpaces(ip.src, j+1, to)
+	if k < to && ip.src[k] != ')' {
+		var ok bool
+		link.Destination, link.Title, k, ok = ip.parseLinkDestination(k, to)
+		if !ok {
+			return -1
+		}
+		k = skipSpaces(ip.src, k, to)
+	}
+	if k >= to || ip.src[k] != ')' {
+		return -1
+	}
+
+	return k + 1
+}
+
+// maxLabelLength is the most characters a link label may have, as in
+// CommonMark, so that brackets nested deeply are not each taken as a label.
+const maxLabelLength = 999
+
+// parseLinkReference parses the label of the reference link whose text is
+// src[i+1:end], returning the index after the link, or -1 if the label is
+// empty or too long.
+func (ip *inlineParser) parseLinkReference(link *Link, i, end, to int) int {
+	from, until := i+1, end
+	next := end + 1
+
+	if next < to && ip.src[next] == '[' {
+		if close := ip.indexOnLine(next+1, to, ']'); close != -1 {
+			if close > next+1 {
+				from, until = next+1, close
+			}
+			link.Source = string(ip.src[next : close+1])
+			next = close + 1
+		}
+	}
+	if until-from > maxLabelLength {
+		return -1
+	}
+	label := string(ip.src[from:until])
+	if normalizeLabel(label) == "" {
+		return -1
+	}
+	link.Reference = label
+
+	return next
+}
+
+// skipSpaces returns the index of the first character in src[j:to] which is
+// not a space, tab or new line, or to if there is none.
+func skipSpaces(src []rune, j, to int) int {
+	for j < to && (src[j] == ' ' || src[j] == '\t' || src[j] == '\n') {
+		j++
+	}
+
+	return j
+}
+
+// maxLinkParens is how deeply parentheses may nest in a link destination,
+// so that a run of unclosed ones is not searched to the end of the text.
+const maxLinkParens = 32
+
+// parseLinkDestination parses the destination of a link starting at
+// src[j], either in angle brackets or up to the next space, and the title
+// in quotes or parentheses which may follow it. next is the index after
+// them.
+func (ip *inlineParser) parseLinkDestination(j, to int) (destination, title string, next int, ok bool) {
+	src := ip.src
+	k := j
+	if src[j] == '<' {
+		for k = j + 1; k < to && src[k] != '>'; k++ {
+			if src[k] == '\n' || src[k] == '<' {
+				return "", "", j, false
+			}
+			if isEscape(src, k, to) {
+				k++
+			}
+		}
+		if k == to {
+			return "", "", j, false
+		}
+		destination = unescape(string(src[j+1 : k]))
+		k++
+	} else {
+		depth := 0
+	loop:
+		for ; k < to; k++ {
+			switch src[k] {
+			case '\\':
+				if isEscape(src, k, to) {
+					k++
+				}
+			case ' ', '\t', '\n':
+				break loop
+			case '(':
+				depth++
+				if depth > maxLinkParens {
+					return "", "", j, false
+				}
+			case ')':
+				if depth == 0 {
+					break loop
+				}
+				depth--
+			}
+		}
+		if k == j {
+			return "", "", j, false
+		}
+		destination = unescape(string(src[j:k]))
+	}
+
+	t := skipSpaces(src, k, to)
+	if t == k || t == to {
+		return destination, "", k, true
+	}
+	closer := src[t]
+	switch closer {
+	case '"', '\'':
+	case '(':
+		closer = ')'
+	default:
+		return destination, "", k, true
+	}
+	if e := ip.find(t+1, to, search{r: closer, escapes: true}); e != -1 {
+		return destination, unescape(string(src[t+1 : e])), e + 1, true
+	}
+
+	return "", "", j, false
+}
+
+// parseWikiLink parses an Obsidian link to another note, [[Note]],
+// [[Note|text]] or [[Note#Heading]], resolving its URL.
+func (ip *inlineParser) parseWikiLink(i, to int) (Node, int, error) {
+	if !ip.p.opts.Wikilinks || !strings.HasPrefix(string(ip.src[i:min(i+2, to)]), "[[") {
+		return nil, i, nil
+	}
+	end := ip.indexOnLine(i+2, to, ']')
+	if end == -1 || end+1 == to || ip.src[end+1] != ']' {
+		return nil, i, nil
+	}
+
+	target, textStart := end, end
+	if bar := ip.indexOnLine(i+2, end, '|'); bar != -1 {
+		target, textStart = bar, bar+1
+	}
+	note, heading, _ := strings.Cut(string(ip.src[i+2:target]), "#")
+	note, heading = strings.TrimSpace(note), strings.TrimSpace(heading)
+	if note == "" && heading == "" {
+		return nil, i, nil
+	}
+
+	link := &WikiLink{Position: ip.position(i), Note: note, Heading: heading}
+	if note == "" {
+		link.Destination = "#" + Slugify(heading)
+	} else if url, ok := ip.p.opts.NoteResolver.ResolveNote(note); ok {
+		link.Destination = url
+		if heading != "" {
+			link.Destination += "#" + Slugify(heading)
+		}
+	} else {
+		link.Unresolved = true
+	}
+
+	if textStart < end {
+		children, err := ip.parse(textStart, end)
+		if err != nil {
+			return nil, i, err
+		}
+		link.Children = children
+	} else {
+		text := note
+		switch {
+		case note == "":
+			text = heading
+		case heading != "":
+			text = note + " > " + heading
+		}
+		link.Children = []Node{&Text{Position: ip.position(i + 2), Value: text}}
+	}
+
+	return link, end + 2, nil
+}
+
+// parseMarkdownImage parses an image of the form ![alt text](path "title").
+func (ip *inlineParser) parseMarkdownImage(i, to int) (Node, int, error) {
+	if i+1 == to || ip.src[i+1] != '[' {
+		return nil, i, nil
+	}
+	end := ip.closingBracket(i+1, to)
+	if end == -1 {
+		return nil, i, nil
+	}
+
+	link := &Link{}
+	next := ip.parseInlineLink(link, end+1, to)
+	if next == -1 || link.Destination == "" {
+		return nil, i, nil
+	}
+	alt, err := ip.parse(i+2, end)
+	if err != nil {
+		return nil, i, err
+	}
+
+	return &Image{
+		Position: ip.position(i),
+		Name:     link.Destination,
+		Alt:      plainText(alt),
+		Title:    link.Title,
+	}, next, nil
+}
+
+// plainText returns the text of nodes without any markup, such as the
+// "a cat" of ![a *cat*](cat.png).
+func plainText(nodes []Node) string {
+	sb := strings.Builder{}
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *Text:
+			sb.WriteString(n.Value)
+		case *CodeSpan:
+			sb.WriteString(n.Value)
+		case *SoftBreak:
+			sb.WriteString(" ")
+		case *Emphasis:
+			sb.WriteString(plainText(n.Children))
+		case *Strong:
+			sb.WriteString(plainText(n.Children))
+		case *Strikethrough:
+			sb.WriteString(plainText(n.Children))
+		case *Highlight:
+			sb.WriteString(plainText(n.Children))
+		case *Superscript:
+			sb.WriteString(plainText(n.Children))
+		case *Subscript:
+			sb.WriteString(plainText(n.Children))
+		case *Link:
+			sb.WriteString(plainText(n.Children))
+		case *WikiLink:
+			sb.WriteString(plainText(n.Children))
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,997 @@
+package converter
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parser turns the lines of a Markdown document into a Document, one
+// Paragraph at a time. It holds the state of parsing a single document, so
+// that documents parsed at the same time do not share footnote numbers.
+type parser struct {
+	opts Options
+	r    *bufio.Reader
+
+	raw        string      // the line being parsed, without its new line
+	line       string      // raw, without the prefixes of the blocks it is in
+	lineColumn int         // of the start of line within raw, starting at 1
+	lineNumber int         // of raw, starting at 1
+	containers []container // the blocks being parsed, outermost first
+	more       bool        // false once every line of the block has been parsed
+	eof        bool        // true once every line of the input has been read
+	pending    []string    // lines read ahead of raw
+	err        error       // from reading r
+
+	footnoteNumberMap    map[int]int
+	inlineFootnoteNumber int
+
+	linkDefinitions map[string]linkDefinition // by normalized label
+	undefinedLinks  map[string][]*Link        // by normalized label
+	shortcutLinks   map[string][]shortcutLink // by normalized label
+	paragraphs      int                       // returned by nextParagraph
+	streaming       bool                      // set by Convert, which writes paragraphs as they are parsed
+
+	headingIDs map[string]bool // the ids given to headings so far
+
+	frontMatter string
+}
+
+// shortcutLink is an undefined shortcut reference link, [label], and the
+// number of the paragraph it is in. Unlike [text][label], when streaming it
+// does not hold back the paragraphs after it: it only waits for the
+// definitions straight after its paragraph, so that text such as "[sic]" is
+// not held until the end of the document.
+type shortcutLink struct {
+	link      *Link
+	paragraph int
+}
+
+// linkDefinition is a [label]: destination "title" line.
+type linkDefinition struct {
+	destination string
+	title       string
+}
+
+func newParser(r io.Reader, opts Options) *parser {
+	p := &parser{
+		opts:              opts,
+		r:                 bufio.NewReader(r),
+		footnoteNumberMap: map[int]int{},
+		linkDefinitions:   map[string]linkDefinition{},
+		undefinedLinks:    map[string][]*Link{},
+		shortcutLinks:     map[string][]shortcutLink{},
+		headingIDs:        map[string]bool{},
+	}
+	p.advance()
+	p.parseFrontMatter()
+
+	return p
+}
+
+// maxFrontMatterLines is the most lines front matter may have, so that a
+// document starting with a thematic break is not read to the end first.
+const maxFrontMatterLines = 100
+
+// parseFrontMatter skips the front matter at the top of the document: the
+// lines between a first line of "---" and the next line of "---" or "...".
+// Front matter must look like YAML, with at least one "key:" line, and have
+// at most maxFrontMatterLines lines; otherwise, the first line is a
+// thematic break instead.
+func (p *parser) parseFrontMatter() {
+	if p.line != "---" {
+		return
+	}
+
+	hasKey := false
+	for i := 0; i <= maxFrontMatterLines; i++ {
+		if i == len(p.pending) {
+			line, ok := p.read()
+			if !ok {
+				return
+			}
+			p.pending = append(p.pending, line)
+		}
+
+		line := p.pending[i]
+		switch {
+		case line == "---" || line == "...":
+			if !hasKey {
+				return
+			}
+			p.frontMatter = strings.Join(p.pending[:i], "\n")
+			p.lineNumber += i + 1
+			p.pending = p.pending[i+1:]
+			p.advance()
+			return
+		case isYAMLKey(line):
+			hasKey = true
+		case !isYAMLLine(line):
+			return
+		}
+	}
+}
+
+// isYAMLKey reports whether line starts a mapping, as in "title: Post".
+func isYAMLKey(line string) bool {
+	key, value, ok := strings.Cut(line, ":")
+	if !ok || key == "" || strings.ContainsAny(key[:1], " \t#-") {
+		return false
+	}
+
+	return value == "" || value[0] == ' ' || value[0] == '\t'
+}
+
+// isYAMLLine reports whether line may be in YAML other than as a key: it is
+// blank, indented, a comment or an item of a list.
+func isYAMLLine(line string) bool {
+	return isBlank(line) || line[0] == ' ' || line[0] == '\t' || line[0] == '#' ||
+		line == "-" || strings.HasPrefix(line, "- ")
+}
+
+// read reads the next line of the input. Carriage returns are removed. An
+// error from the reader is kept as a ParseError at the line being read.
+func (p *parser) read() (string, bool) {
+	line, err := p.r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err != io.EOF {
+			pos := Position{Line: p.lineNumber + len(p.pending) + 1, Column: 1}
+			p.err = errorAt(pos, err, "cannot read line")
+		}
+		return "", false
+	}
+
+	return strings.ReplaceAll(strings.TrimSuffix(line, "\n"), "\r", ""), true
+}
+
+// advance moves on to the next line of the input.
+func (p *parser) advance() {
+	var raw string
+	ok := true
+	if len(p.pending) > 0 {
+		raw, p.pending = p.pending[0], p.pending[1:]
+	} else {
+		raw, ok = p.read()
+	}
+	if !ok {
+		p.raw, p.line, p.more, p.eof = "", "", false, true
+		return
+	}
+
+	p.raw = raw
+	p.lineNumber++
+	p.setLine()
+}
+
+// container is a block whose lines start with a prefix: either indent
+// columns of indentation, for the contents of a list item, or a '>', for a
+// blockquote.
+type container struct {
+	indent int
+	quote  bool
+}
+
+// strip removes the prefixes of the blocks being parsed from raw, returning
+// what is left and the number of characters removed. ok is false if raw does
+// not belong to the innermost block. A blank line belongs to a list item,
+// but ends a blockquote.
+func (p *parser) strip(raw string) (line string, stripped int, ok bool) {
+	line = raw
+	for _, c := range p.containers {
+		var n int
+		switch {
+		case c.quote:
+			if !isBlockquote(line) {
+				return line, stripped, false
+			}
+			n = 1
+			if strings.HasPrefix(line, "> ") {
+				n = 2
+			}
+			line = line[n:]
+		case !isBlank(line) && indentWidth(line) < c.indent:
+			return line, stripped, false
+		default:
+			line, n = dedent(line, c.indent)
+		}
+		stripped += n
+	}
+
+	return line, stripped, true
+}
+
+// setLine strips the prefixes of the blocks being parsed from raw. A line
+// which does not belong to the innermost block ends it.
+func (p *parser) setLine() {
+	var stripped int
+	var ok bool
+	p.line, stripped, ok = p.strip(p.raw)
+	p.lineColumn = stripped + 1
+	p.more = !p.eof && ok
+}
+
+// enter starts parsing the contents of c, which starts on the current line.
+func (p *parser) enter(c container) {
+	p.containers = append(p.containers, c)
+	p.setLine()
+}
+
+// leave stops parsing the contents of the innermost block.
+func (p *parser) leave() {
+	p.containers = p.containers[:len(p.containers)-1]
+	p.setLine()
+}
+
+// peekNonBlank returns the next line after raw which is not blank once the
+// prefixes of the blocks being parsed have been removed, without moving on
+// to it. ok is false if there is none, or if a line which does not belong
+// to the innermost block comes first.
+func (p *parser) peekNonBlank() (line string, ok bool) {
+	for i := 0; ; i++ {
+		if i == len(p.pending) {
+			raw, ok := p.read()
+			if !ok {
+				return "", false
+			}
+			p.pending = append(p.pending, raw)
+		}
+
+		line, _, ok := p.strip(p.pending[i])
+		if !ok {
+			return "", false
+		}
+		if !isBlank(line) {
+			return line, true
+		}
+	}
+}
+
+// tabWidth is the number of columns a tab indents a line by.
+const tabWidth = 4
+
+// indentWidth returns the number of columns of spaces and tabs starting
+// line.
+func indentWidth(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += tabWidth - width%tabWidth
+		default:
+			return width
+		}
+	}
+
+	return width
+}
+
+// dedent removes up to columns of indentation from line, and returns what
+// is left and the number of characters removed.
+func dedent(line string, columns int) (string, int) {
+	width, i := 0, 0
+	for i < len(line) && width < columns {
+		switch line[i] {
+		case ' ':
+			width++
+		case '\t':
+			width += tabWidth - width%tabWidth
+		default:
+			return line[i:], i
+		}
+		i++
+	}
+
+	return line[i:], i
+}
+
+func (p *parser) parse() (*Document, error) {
+	doc := &Document{Position: Position{Line: 1, Column: 1}, FrontMatter: p.frontMatter}
+
+	for {
+		para, err := p.nextParagraph()
+		if err != nil {
+			return nil, err
+		}
+		if para == nil {
+			break
+		}
+		doc.Children = append(doc.Children, para)
+	}
+
+	return doc, nil
+}
+
+// nextParagraph returns the next Paragraph of the document, or nil once
+// there are none left. Only the lines of that paragraph are held in memory.
+// A paragraph of only link definitions is skipped.
+func (p *parser) nextParagraph() (*Paragraph, error) {
+	for {
+		for p.more && isBlank(p.line) {
+			p.advance()
+		}
+		if !p.more {
+			return nil, p.err
+		}
+
+		para, err := p.parseParagraph()
+		if err != nil {
+			return nil, err
+		}
+		if p.err != nil {
+			return nil, p.err
+		}
+		if len(para.Children) > 0 {
+			// The definitions between this paragraph and the one before
+			// have been read, so when streaming the shortcut links before
+			// it are left undefined, unless they are being held for
+			// another link.
+			if p.streaming && len(p.undefinedLinks) == 0 {
+				p.dropShortcutLinks(p.paragraphs)
+			}
+			p.paragraphs++
+			return para, nil
+		}
+	}
+}
+
+// lineStart returns the position of the start of the current line, after
+// the prefixes of the blocks it is in.
+func (p *parser) lineStart() Position {
+	return Position{Line: p.lineNumber, Column: p.lineColumn}
+}
+
+func (p *parser) parseParagraph() (*Paragraph, error) {
+	para := &Paragraph{Position: p.lineStart()}
+
+	for p.more && !isBlank(p.line) {
+		block, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		if block != nil {
+			para.Children = append(para.Children, block)
+		}
+	}
+
+	return para, nil
+}
+
+func (p *parser) parseBlock() (Node, error) {
+	line := p.line
+
+	switch {
+	case headingLevel(line) > 0:
+		return p.parseHeading()
+	case isCodeFence(line):
+		return p.parseCodeBlock(), nil
+	case isThematicBreak(line):
+		block := &ThematicBreak{Position: p.lineStart()}
+		p.advance()
+		return block, nil
+	case isListItem(line):
+		return p.parseList()
+	case isBlockquote(line):
+		return p.parseBlockquote()
+	case p.isTableRow(line):
+		return p.parseTable()
+	case p.isFootnoteDefinition(line):
+		return p.parseFootnoteDefinition()
+	case isLinkDefinition(line):
+		p.parseLinkDefinition()
+		return nil, nil
+	default:
+		return p.parseTextBlock()
+	}
+}
+
+// startsBlock reports whether line begins something other than plain text.
+// A link definition does not, as it cannot interrupt text: a line such as
+// "[Update]: fixed" in the middle of a paragraph is part of it.
+func (p *parser) startsBlock(line string) bool {
+	return headingLevel(line) > 0 ||
+		isCodeFence(line) ||
+		isThematicBreak(line) ||
+		isListItem(line) ||
+		isBlockquote(line) ||
+		p.isTableRow(line) ||
+		p.isFootnoteDefinition(line)
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// headingLevel returns the number of '#' starting line, or 0 if line is not
+// a heading.
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level == len(line) || line[level] != ' ' {
+		return 0
+	}
+
+	return level
+}
+
+func (p *parser) parseHeading() (*Heading, error) {
+	line := p.line
+	level := headingLevel(line)
+	heading := &Heading{Position: p.lineStart(), Level: level}
+
+	// The space after the '#'s is kept as part of the text.
+	children, err := p.parseInlines(line[level:], Position{Line: p.lineNumber, Column: p.lineColumn + level}, p.lineColumn)
+	if err != nil {
+		return nil, err
+	}
+	heading.Children = children
+	heading.ID = p.headingID(plainText(children))
+	p.advance()
+
+	return heading, nil
+}
+
+// headingID returns the slug of a heading's text, followed by "-1", "-2" and
+// so on if an earlier heading has the same one, so that ids are unique.
+func (p *parser) headingID(text string) string {
+	slug := Slugify(text)
+	if slug == "" {
+		return ""
+	}
+	id := slug
+	for n := 1; p.headingIDs[id]; n++ {
+		id = slug + "-" + strconv.Itoa(n)
+	}
+	p.headingIDs[id] = true
+
+	return id
+}
+
+func isCodeFence(line string) bool {
+	return strings.HasPrefix(line, "```")
+}
+
+func (p *parser) parseCodeBlock() *CodeBlock {
+	block := &CodeBlock{
+		Position: p.lineStart(),
+		Info:     strings.TrimSpace(strings.TrimPrefix(p.line, "```")),
+	}
+	p.advance()
+
+	for p.more {
+		line := p.line
+		p.advance()
+		if isCodeFence(line) {
+			break
+		}
+		block.Lines = append(block.Lines, line)
+	}
+
+	return block
+}
+
+// isThematicBreak reports whether line is three or more of the same '-',
+// '*' or '_', and nothing else but spaces.
+func isThematicBreak(line string) bool {
+	if line == "" || !strings.ContainsRune("-*_", rune(line[0])) {
+		return false
+	}
+	count := 0
+	for _, r := range line {
+		switch r {
+		case rune(line[0]):
+			count++
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+
+	return count >= 3
+}
+
+// listMarker returns the length of the marker starting a list item: a '-',
+// or a number followed by '.' or ')' for an ordered list. n is 0 if line is
+// not a list item.
+func listMarker(line string) (n int, ordered bool) {
+	if line == "-" || strings.HasPrefix(line, "- ") {
+		return 1, false
+	}
+
+	digits := 0
+	for digits < len(line) && digits < 9 && '0' <= line[digits] && line[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 || digits == len(line) || (line[digits] != '.' && line[digits] != ')') {
+		return 0, false
+	}
+	if rest := line[digits+1:]; rest != "" && rest[0] != ' ' {
+		return 0, false
+	}
+
+	return digits + 1, true
+}
+
+func isListItem(line string) bool {
+	n, _ := listMarker(line)
+	return n > 0
+}
+
+// parseList parses a run of list items of the same kind. An ordered list
+// starts at the number of its first item.
+func (p *parser) parseList() (*List, error) {
+	list := &List{Position: p.lineStart()}
+	n, ordered := listMarker(p.line)
+	if ordered {
+		list.Ordered = true
+		list.Start, _ = strconv.Atoi(p.line[:n-1])
+	}
+
+	for p.more {
+		// Blank lines between items do not end the list, as long as the
+		// next item is of the same kind.
+		if isBlank(p.line) {
+			next, ok := p.peekNonBlank()
+			if n, ordered := listMarker(next); !ok || n == 0 || ordered != list.Ordered || isThematicBreak(next) {
+				break
+			}
+			for isBlank(p.line) {
+				p.advance()
+			}
+		}
+
+		n, ordered := listMarker(p.line)
+		if n == 0 || ordered != list.Ordered {
+			break
+		}
+		item, err := p.parseListItem(n)
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, item)
+	}
+
+	return list, nil
+}
+
+// parseListItem parses a list item whose marker is n long. Indented lines
+// straight after it continue its text. After that, lines indented further
+// than the marker, including those after a blank line, are nested lists or
+// further paragraphs of the item.
+func (p *parser) parseListItem(n int) (*ListItem, error) {
+	item := &ListItem{Position: p.lineStart()}
+	if checked, ok := taskMarker(p.line[n:]); ok {
+		item.Task, item.Checked = true, checked
+		n += len(" [ ]")
+	}
+
+	// The space after the marker is kept as part of the text.
+	lines := []string{p.line[n:]}
+	p.advance()
+	for p.more && !isBlank(p.line) && indentWidth(p.line) > 0 && !p.startsBlock(strings.TrimLeft(p.line, " \t")) {
+		lines = append(lines, p.line)
+		p.advance()
+	}
+
+	start := Position{Line: item.Line, Column: item.Column + n}
+	children, err := p.parseInlines(strings.Join(lines, "\n"), start, item.Column)
+	if err != nil {
+		return nil, err
+	}
+	item.Children = children
+
+	for p.more {
+		if isBlank(p.line) {
+			next, ok := p.peekNonBlank()
+			if !ok || indentWidth(next) == 0 {
+				break
+			}
+			for isBlank(p.line) {
+				p.advance()
+			}
+		} else if indentWidth(p.line) == 0 {
+			break
+		}
+
+		child, err := p.parseIndented()
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			item.Children = append(item.Children, child)
+		}
+	}
+
+	return item, nil
+}
+
+// taskMarker reports whether the text of a list item starts with " [ ]" or
+// " [x]", and which.
+func taskMarker(text string) (checked, ok bool) {
+	if len(text) < 4 || (len(text) > 4 && text[4] != ' ') {
+		return false, false
+	}
+	switch text[:4] {
+	case " [ ]":
+		return false, true
+	case " [x]", " [X]":
+		return true, true
+	}
+
+	return false, false
+}
+
+// parseIndented parses the indented list or paragraph starting at the
+// current line, up to the first line which is less indented. A paragraph of
+// only link definitions is nil.
+func (p *parser) parseIndented() (Node, error) {
+	p.enter(container{indent: indentWidth(p.line)})
+	defer p.leave()
+
+	if isListItem(p.line) {
+		return p.parseList()
+	}
+	para, err := p.parseParagraph()
+	if err != nil || len(para.Children) == 0 {
+		return nil, err
+	}
+
+	return para, nil
+}
+
+func isBlockquote(line string) bool {
+	return strings.HasPrefix(line, ">")
+}
+
+// parseBlockquote parses a run of lines starting with '>'. Once the '>' is
+// removed, its lines are parsed into paragraphs like those of a document.
+// A blockquote whose first line is a callout marker is a Callout.
+func (p *parser) parseBlockquote() (Node, error) {
+	pos := p.lineStart()
+	p.enter(container{quote: true})
+	defer p.leave()
+
+	var callout *Callout
+	if p.opts.Callouts {
+		var err error
+		callout, err = p.parseCalloutTitle(pos)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var children []Node
+	for p.more {
+		if isBlank(p.line) {
+			p.advance()
+			continue
+		}
+		para, err := p.parseParagraph()
+		if err != nil {
+			return nil, err
+		}
+		if len(para.Children) > 0 {
+			children = append(children, para)
+		}
+	}
+
+	if callout != nil {
+		callout.Children = children
+		return callout, nil
+	}
+
+	return &Blockquote{Position: pos, Children: children}, nil
+}
+
+// calloutMarker returns the type of the callout marker starting line, such
+// as "note" for "[!NOTE]", and the length of the marker, including a '-' or
+// '+' after it. n is 0 if line does not start with a callout marker.
+func calloutMarker(line string) (calloutType string, n int) {
+	if !strings.HasPrefix(line, "[!") {
+		return "", 0
+	}
+	end := strings.IndexFunc(line[2:], func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '-' || r == '_')
+	}) + 2
+	if end < 3 || line[end] != ']' {
+		return "", 0
+	}
+
+	n = end + 1
+	if n < len(line) && (line[n] == '-' || line[n] == '+') {
+		n++
+	}
+	if n < len(line) && line[n] != ' ' {
+		return "", 0
+	}
+
+	return strings.ToLower(line[2:end]), n
+}
+
+// parseCalloutTitle parses the first line of a blockquote if it is a callout
+// marker, returning nil if it is not.
+func (p *parser) parseCalloutTitle(pos Position) (*Callout, error) {
+	calloutType, n := calloutMarker(p.line)
+	if n == 0 {
+		return nil, nil
+	}
+
+	callout := &Callout{Position: pos, Type: calloutType}
+	switch p.line[n-1] {
+	case '-':
+		callout.Foldable = true
+	case '+':
+		callout.Foldable, callout.Open = true, true
+	}
+
+	title := strings.TrimLeft(p.line[n:], " ")
+	start := Position{Line: p.lineNumber, Column: p.lineColumn + len(p.line) - len(title)}
+	title = strings.TrimRight(title, " ")
+	if title != "" {
+		children, err := p.parseInlines(title, start, start.Column)
+		if err != nil {
+			return nil, err
+		}
+		callout.Title = children
+	}
+	p.advance()
+
+	return callout, nil
+}
+
+func (p *parser) isTableRow(line string) bool {
+	return p.opts.Tables && strings.HasPrefix(line, "|")
+}
+
+// isTableDelimiterRow reports whether line is made up of only the '|', '-',
+// ':' and ' ' used to separate the header of a table from its body.
+func isTableDelimiterRow(line string) bool {
+	return strings.Trim(line, "|-: \t") == "" && strings.Contains(line, "-")
+}
+
+func (p *parser) parseTable() (*Table, error) {
+	table := &Table{Position: p.lineStart()}
+	header, err := p.parseTableRow()
+	if err != nil {
+		return nil, err
+	}
+	table.Header = header
+	for _, cell := range table.Header.Cells {
+		cell.Header = true
+	}
+
+	if p.more && isTableDelimiterRow(p.line) {
+		p.advance()
+	}
+
+	for p.more && p.isTableRow(p.line) {
+		row, err := p.parseTableRow()
+		if err != nil {
+			return nil, err
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
+
+func (p *parser) parseTableRow() (*TableRow, error) {
+	line := p.line
+	row := &TableRow{Position: p.lineStart()}
+
+	// Skip the leading '|'; a trailing '|' does not start another cell.
+	column := p.lineColumn + 1
+	cells := splitTableRow(line[1:])
+	if len(cells) > 1 && cells[len(cells)-1] == "" {
+		cells = cells[:len(cells)-1]
+	}
+
+	for _, cell := range cells {
+		pos := Position{Line: p.lineNumber, Column: column}
+		children, err := p.parseInlines(cell, pos, p.lineColumn)
+		if err != nil {
+			return nil, err
+		}
+		row.Cells = append(row.Cells, &TableCell{Position: pos, Children: children})
+		column += len([]rune(cell)) + 1
+	}
+	p.advance()
+
+	return row, nil
+}
+
+// splitTableRow splits s into cells at each '|' which is not escaped by a
+// backslash or within a wikilink or image, as in [[Note|text]]. The
+// backslashes are left in the cells.
+func splitTableRow(s string) []string {
+	var cells []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			if !strings.HasPrefix(s[i:], "[[") {
+				break
+			}
+			if end := strings.Index(s[i+2:], "]]"); end != -1 {
+				i += end + 3
+			}
+		case '|':
+			cells = append(cells, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(cells, s[start:])
+}
+
+// footnoteLabelEnd returns the index of the ']' closing a "[^" at the start
+// of s, or -1 if s does not start with a footnote.
+func footnoteLabelEnd(s string) int {
+	if !strings.HasPrefix(s, "[^") {
+		return -1
+	}
+	end := strings.IndexAny(s, "]\n")
+	if end == -1 || s[end] != ']' {
+		return -1
+	}
+
+	return end
+}
+
+func (p *parser) isFootnoteDefinition(line string) bool {
+	if !p.opts.Footnotes {
+		return false
+	}
+	end := footnoteLabelEnd(line)
+
+	return end != -1 && strings.HasPrefix(line[end+1:], ":")
+}
+
+func (p *parser) parseFootnoteDefinition() (*FootnoteDefinition, error) {
+	line := p.line
+	end := footnoteLabelEnd(line)
+	label := line[2:end]
+
+	footnoteNumber, err := strconv.Atoi(label)
+	if err != nil {
+		return nil, errorAt(p.lineStart(), err, "invalid footnote number %q", label)
+	}
+
+	// The text after the ':' is kept as-is, including the space before it.
+	textPos := Position{Line: p.lineNumber, Column: p.lineColumn + len([]rune(line[:end+2]))}
+	def := &FootnoteDefinition{
+		Position: p.lineStart(),
+		Label:    label,
+		Number:   p.footnoteNumberMap[footnoteNumber],
+		Children: []Node{&Text{Position: textPos, Value: p.typographyText(line[end+2:])}},
+	}
+	p.advance()
+
+	return def, nil
+}
+
+// splitLinkDefinition splits a [label]: destination "title" line. ok is
+// false if line is not a link definition.
+func splitLinkDefinition(line string) (label string, def linkDefinition, ok bool) {
+	if !strings.HasPrefix(line, "[") || strings.HasPrefix(line, "[^") {
+		return "", def, false
+	}
+	end := strings.Index(line, "]")
+	if end == -1 || strings.ContainsRune(line[1:end], '[') || !strings.HasPrefix(line[end+1:], ":") {
+		return "", def, false
+	}
+	label = normalizeLabel(line[1:end])
+	if label == "" {
+		return "", def, false
+	}
+
+	ip := inlineParser{src: []rune(line[end+2:])}
+	j := skipSpaces(ip.src, 0, len(ip.src))
+	if j == len(ip.src) {
+		return "", def, false
+	}
+	def.destination, def.title, j, ok = ip.parseLinkDestination(j, len(ip.src))
+	if !ok || skipSpaces(ip.src, j, len(ip.src)) != len(ip.src) {
+		return "", def, false
+	}
+
+	return label, def, true
+}
+
+func isLinkDefinition(line string) bool {
+	_, _, ok := splitLinkDefinition(line)
+	return ok
+}
+
+// parseLinkDefinition records a link definition, giving its destination to
+// any links to it which have already been parsed. Only the first
+// definition of a label is used.
+func (p *parser) parseLinkDefinition() {
+	label, def, _ := splitLinkDefinition(p.line)
+	p.advance()
+
+	if _, ok := p.linkDefinitions[label]; ok {
+		return
+	}
+	p.linkDefinitions[label] = def
+	for _, link := range p.undefinedLinks[label] {
+		link.Destination, link.Title, link.Undefined = def.destination, def.title, false
+	}
+	delete(p.undefinedLinks, label)
+	for _, shortcut := range p.shortcutLinks[label] {
+		shortcut.link.Destination, shortcut.link.Title, shortcut.link.Undefined = def.destination, def.title, false
+	}
+	delete(p.shortcutLinks, label)
+}
+
+// defineLink gives link the destination of its reference, if it has been
+// defined, or otherwise holds on to it until the definition is read.
+func (p *parser) defineLink(link *Link) {
+	label := normalizeLabel(link.Reference)
+	if def, ok := p.linkDefinitions[label]; ok {
+		link.Destination, link.Title = def.destination, def.title
+		return
+	}
+	link.Undefined = true
+	if link.Source == "" {
+		p.shortcutLinks[label] = append(p.shortcutLinks[label], shortcutLink{link: link, paragraph: p.paragraphs})
+		return
+	}
+	p.undefinedLinks[label] = append(p.undefinedLinks[label], link)
+}
+
+// dropShortcutLinks stops waiting for the definitions of the shortcut links
+// in the paragraphs before paragraph, which stay undefined.
+func (p *parser) dropShortcutLinks(paragraph int) {
+	for label, shortcuts := range p.shortcutLinks {
+		waiting := shortcuts[:0]
+		for _, shortcut := range shortcuts {
+			if shortcut.paragraph >= paragraph {
+				waiting = append(waiting, shortcut)
+			}
+		}
+		if len(waiting) == 0 {
+			delete(p.shortcutLinks, label)
+		} else {
+			p.shortcutLinks[label] = waiting
+		}
+	}
+}
+
+// dropUndefinedLinks stops waiting for the definitions of the links which
+// are not yet defined, leaving them to be written as they are.
+func (p *parser) dropUndefinedLinks() {
+	clear(p.undefinedLinks)
+	clear(p.shortcutLinks)
+}
+
+// normalizeLabel returns label in lower case, with each run of spaces
+// replaced by a single space, so that labels match regardless of either.
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+func (p *parser) parseTextBlock() (*TextBlock, error) {
+	block := &TextBlock{Position: p.lineStart()}
+
+	lines := []string{p.line}
+	p.advance()
+	for p.more && !isBlank(p.line) && !p.startsBlock(p.line) {
+		lines = append(lines, p.line)
+		p.advance()
+	}
+
+	children, err := p.parseInlines(strings.Join(lines, "\n"), block.Position, block.Column)
+	if err != nil {
+		return nil, err
+	}
+	block.Children = children
+
+	return block, nil
+}
@@ -0,0 +1,183 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := "# Title\n\nSome *text*.[^1]\n\n- one\n- `two`\n\n```go\nx := 1\n```\n\n| a | b |\n|-|-|\n| c | d |\n\n![[image_name.png]]\n\n[^1]: A note."
+
+	doc, err := New(DefaultOptions()).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("TestParse unexpected error: %v", err)
+	}
+
+	expected := &Document{
+		Position: Position{Line: 1, Column: 1},
+		Children: []Node{
+			&Paragraph{Position: Position{Line: 1, Column: 1}, Children: []Node{
+				&Heading{Position: Position{Line: 1, Column: 1}, Level: 1, ID: "title", Children: []Node{
+					&Text{Position: Position{Line: 1, Column: 2}, Value: " Title"},
+				}},
+			}},
+			&Paragraph{Position: Position{Line: 3, Column: 1}, Children: []Node{
+				&TextBlock{Position: Position{Line: 3, Column: 1}, Children: []Node{
+					&Text{Position: Position{Line: 3, Column: 1}, Value: "Some "},
+					&Emphasis{Position: Position{Line: 3, Column: 6}, Children: []Node{
+						&Text{Position: Position{Line: 3, Column: 7}, Value: "text"},
+					}},
+					&Text{Position: Position{Line: 3, Column: 12}, Value: "."},
+					&FootnoteReference{Position: Position{Line: 3, Column: 13}, Label: "1", Number: 1},
+				}},
+			}},
+			&Paragraph{Position: Position{Line: 5, Column: 1}, Children: []Node{
+				&List{Position: Position{Line: 5, Column: 1}, Items: []*ListItem{
+					{Position: Position{Line: 5, Column: 1}, Children: []Node{
+						&Text{Position: Position{Line: 5, Column: 2}, Value: " one"},
+					}},
+					{Position: Position{Line: 6, Column: 1}, Children: []Node{
+						&Text{Position: Position{Line: 6, Column: 2}, Value: " "},
+						&CodeSpan{Position: Position{Line: 6, Column: 3}, Value: "two"},
+					}},
+				}},
+			}},
+			&Paragraph{Position: Position{Line: 8, Column: 1}, Children: []Node{
+				&CodeBlock{Position: Position{Line: 8, Column: 1}, Info: "go", Lines: []string{"x := 1"}},
+			}},
+			&Paragraph{Position: Position{Line: 12, Column: 1}, Children: []Node{
+				&Table{
+					Position: Position{Line: 12, Column: 1},
+					Header: &TableRow{Position: Position{Line: 12, Column: 1}, Cells: []*TableCell{
+						{Position: Position{Line: 12, Column: 2}, Header: true, Children: []Node{&Text{Position: Position{Line: 12, Column: 2}, Value: " a "}}},
+						{Position: Position{Line: 12, Column: 6}, Header: true, Children: []Node{&Text{Position: Position{Line: 12, Column: 6}, Value: " b "}}},
+					}},
+					Rows: []*TableRow{
+						{Position: Position{Line: 14, Column: 1}, Cells: []*TableCell{
+							{Position: Position{Line: 14, Column: 2}, Children: []Node{&Text{Position: Position{Line: 14, Column: 2}, Value: " c "}}},
+							{Position: Position{Line: 14, Column: 6}, Children: []Node{&Text{Position: Position{Line: 14, Column: 6}, Value: " d "}}},
+						}},
+					},
+				},
+			}},
+			&Paragraph{Position: Position{Line: 16, Column: 1}, Children: []Node{
+				&TextBlock{Position: Position{Line: 16, Column: 1}, Children: []Node{
+					&Image{Position: Position{Line: 16, Column: 1}, Name: "image_name.png"},
+				}},
+			}},
+			&Paragraph{Position: Position{Line: 18, Column: 1}, Children: []Node{
+				&FootnoteDefinition{Position: Position{Line: 18, Column: 1}, Label: "1", Number: 1, Children: []Node{
+					&Text{Position: Position{Line: 18, Column: 6}, Value: " A note."},
+				}},
+			}},
+		},
+	}
+
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("TestParse expected: \n%#v \nbut got: \n%#v", expected, doc)
+	}
+}
+
+func TestRenderChangedDocument(t *testing.T) {
+	c := New(DefaultOptions())
+
+	doc, err := c.Parse(strings.NewReader("# Title\n\nSome text."))
+	if err != nil {
+		t.Fatalf("TestRenderChangedDocument unexpected error: %v", err)
+	}
+
+	// Demote every heading by one level, without parsing again.
+	for _, para := range doc.Children {
+		for _, child := range para.(*Paragraph).Children {
+			if heading, ok := child.(*Heading); ok {
+				heading.Level++
+			}
+		}
+	}
+
+	sb := strings.Builder{}
+	err = c.Render(&sb, doc)
+	if err != nil {
+		t.Fatalf("TestRenderChangedDocument unexpected error: %v", err)
+	}
+
+	expected := "<h2 id=\"title\"> Title</h2>\n<p>\nSome text.\n</p>"
+	if sb.String() != expected {
+		t.Errorf("TestRenderChangedDocument expected: \n%s \nbut got: \n%s", expected, sb.String())
+	}
+}
+
+func TestDocumentTasks(t *testing.T) {
+	input := "- [x] One\n- [ ] Two\n  - [x] Two point one\n- Not a task\n\nText.\n\n1. [ ] Three"
+
+	doc, err := New(DefaultOptions()).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("TestDocumentTasks unexpected error: %v", err)
+	}
+
+	expected := TaskSummary{Done: 2, Total: 4}
+	if tasks := doc.Tasks(); tasks != expected {
+		t.Errorf("TestDocumentTasks expected %+v but got %+v", expected, tasks)
+	}
+}
+
+func TestParseShortcutLinks(t *testing.T) {
+	c := New(DefaultOptions())
+
+	// Parse reads the whole document, so unlike Convert it uses
+	// definitions after the next paragraph.
+	doc, err := c.Parse(strings.NewReader("He said [sic] this.\n\nMore.\n\n[sic]: /sic"))
+	if err != nil {
+		t.Fatalf("TestParseShortcutLinks unexpected error: %v", err)
+	}
+
+	sb := strings.Builder{}
+	err = c.Render(&sb, doc)
+	if err != nil {
+		t.Fatalf("TestParseShortcutLinks unexpected error: %v", err)
+	}
+
+	expected := "He said <a href=\"/sic\">sic</a> this.\n<p>\nMore.\n</p>"
+	if sb.String() != expected {
+		t.Errorf("TestParseShortcutLinks expected: \n%s \nbut got: \n%s", expected, sb.String())
+	}
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	input := "---\ntitle: Post\ntags: [a, b]\n---\nText."
+
+	doc, err := New(DefaultOptions()).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("TestParseFrontMatter unexpected error: %v", err)
+	}
+
+	if expected := "title: Post\ntags: [a, b]"; doc.FrontMatter != expected {
+		t.Errorf("TestParseFrontMatter expected front matter: \n%s \nbut got: \n%s", expected, doc.FrontMatter)
+	}
+	if len(doc.Children) != 1 || doc.Children[0].Pos() != (Position{Line: 5, Column: 1}) {
+		t.Errorf("TestParseFrontMatter expected one paragraph at line 5 but got: %#v", doc.Children)
+	}
+}
+
+func TestParseNestedEmphasis(t *testing.T) {
+	doc, err := New(DefaultOptions()).Parse(strings.NewReader("**a *b*** c_"))
+	if err != nil {
+		t.Fatalf("TestParseNestedEmphasis unexpected error: %v", err)
+	}
+
+	expected := []Node{
+		&Strong{Position: Position{Line: 1, Column: 1}, Children: []Node{
+			&Text{Position: Position{Line: 1, Column: 3}, Value: "a "},
+			&Emphasis{Position: Position{Line: 1, Column: 5}, Children: []Node{
+				&Text{Position: Position{Line: 1, Column: 6}, Value: "b"},
+			}},
+		}},
+		&Text{Position: Position{Line: 1, Column: 10}, Value: " c_"},
+	}
+
+	block := doc.Children[0].(*Paragraph).Children[0].(*TextBlock)
+	if !reflect.DeepEqual(block.Children, expected) {
+		t.Errorf("TestParseNestedEmphasis expected: \n%#v \nbut got: \n%#v", expected, block.Children)
+	}
+}
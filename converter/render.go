@@ -0,0 +1,237 @@
+package converter
+
+import (
+	"fmt"
+	"io"
+)
+
+// Renderer writes each kind of node. Methods for nodes with children render
+// them through the RenderContext, so a type embedding HTMLRenderer can
+// change the output for a single kind of node and keep the rest.
+//
+// A Document is written as DocumentStart, then each of its paragraphs
+// separated by new lines, then DocumentEnd. Convert writes each paragraph
+// as soon as it has been parsed, before the rest of the document is read.
+type Renderer interface {
+	DocumentStart(ctx *RenderContext, n *Document) error
+	DocumentEnd(ctx *RenderContext, n *Document) error
+	Paragraph(ctx *RenderContext, n *Paragraph) error
+	Heading(ctx *RenderContext, n *Heading) error
+	TextBlock(ctx *RenderContext, n *TextBlock) error
+	List(ctx *RenderContext, n *List) error
+	ListItem(ctx *RenderContext, n *ListItem) error
+	Blockquote(ctx *RenderContext, n *Blockquote) error
+	Callout(ctx *RenderContext, n *Callout) error
+	ThematicBreak(ctx *RenderContext, n *ThematicBreak) error
+	CodeBlock(ctx *RenderContext, n *CodeBlock) error
+	Table(ctx *RenderContext, n *Table) error
+	TableRow(ctx *RenderContext, n *TableRow) error
+	TableCell(ctx *RenderContext, n *TableCell) error
+	FootnoteDefinition(ctx *RenderContext, n *FootnoteDefinition) error
+	Text(ctx *RenderContext, n *Text) error
+	SoftBreak(ctx *RenderContext, n *SoftBreak) error
+	Emphasis(ctx *RenderContext, n *Emphasis) error
+	Strong(ctx *RenderContext, n *Strong) error
+	Strikethrough(ctx *RenderContext, n *Strikethrough) error
+	Highlight(ctx *RenderContext, n *Highlight) error
+	Superscript(ctx *RenderContext, n *Superscript) error
+	Subscript(ctx *RenderContext, n *Subscript) error
+	CodeSpan(ctx *RenderContext, n *CodeSpan) error
+	FootnoteReference(ctx *RenderContext, n *FootnoteReference) error
+	Link(ctx *RenderContext, n *Link) error
+	WikiLink(ctx *RenderContext, n *WikiLink) error
+	Image(ctx *RenderContext, n *Image) error
+}
+
+// RenderContext is passed to every Renderer method. It writes to the output,
+// keeping the first error, and renders nodes with the Renderer in use.
+type RenderContext struct {
+	w        io.Writer
+	renderer Renderer
+	opts     Options
+	prev     Node
+	err      error
+}
+
+func newRenderContext(w io.Writer, opts Options) *RenderContext {
+	renderer := opts.Renderer
+	if renderer == nil {
+		renderer = HTMLRenderer{}
+	}
+
+	return &RenderContext{w: w, renderer: renderer, opts: opts}
+}
+
+// Options returns the options of the Converter doing the rendering.
+func (c *RenderContext) Options() Options {
+	return c.opts
+}
+
+// Write writes p to the output. Once a write has failed, nothing more is
+// written and the error is returned again.
+func (c *RenderContext) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.err = err
+
+	return n, err
+}
+
+// WriteString writes s to the output, like Write.
+func (c *RenderContext) WriteString(s string) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := io.WriteString(c.w, s)
+	c.err = err
+
+	return n, err
+}
+
+// WriteEscaped writes s to the output as HTML text, with special characters
+// replaced by entities. Character references already in s, such as &copy;,
+// are kept if Options.Entities is set.
+func (c *RenderContext) WriteEscaped(s string) (int, error) {
+	return c.WriteString(escapeText(s, c.opts.Entities))
+}
+
+// EscapeAttribute returns s escaped to be written between the double quotes
+// of an attribute, keeping character references like WriteEscaped.
+func (c *RenderContext) EscapeAttribute(s string) string {
+	return escapeAttribute(s, c.opts.Entities)
+}
+
+// EscapeURL returns the URL s escaped to be written as an href attribute,
+// with the characters which may not appear in a URL percent-encoded. A URL
+// which would run code, such as javascript:alert(1), is written as "#".
+func (c *RenderContext) EscapeURL(s string) string {
+	return escapeURL(s, c.opts.Entities, false)
+}
+
+// EscapeImageURL is EscapeURL for an image's src attribute, which may also
+// be a data:image/ URL.
+func (c *RenderContext) EscapeImageURL(s string) string {
+	return escapeURL(s, c.opts.Entities, true)
+}
+
+// Err returns the first error from writing to the output or from a
+// Renderer method.
+func (c *RenderContext) Err() error {
+	return c.err
+}
+
+// PreviousSibling returns the node rendered before n by RenderChildren, or
+// nil if n is the first of its parent's children.
+func (c *RenderContext) PreviousSibling() Node {
+	return c.prev
+}
+
+// RenderChildren renders each of children in turn, writing sep between them.
+func (c *RenderContext) RenderChildren(children []Node, sep string) error {
+	var prev Node
+	for _, child := range children {
+		if err := c.renderChild(child, prev, sep); err != nil {
+			return err
+		}
+		prev = child
+	}
+
+	return c.err
+}
+
+// renderChild renders n, which follows prev among its parent's children.
+// sep is written first, unless n is the first child.
+func (c *RenderContext) renderChild(n, prev Node, sep string) error {
+	if prev != nil {
+		c.WriteString(sep)
+	}
+	c.prev = prev
+
+	return c.Render(n)
+}
+
+// documentSeparator is written between the paragraphs of a document.
+const documentSeparator = "\n"
+
+func (c *RenderContext) renderDocument(n *Document) error {
+	if err := c.renderer.DocumentStart(c, n); err != nil {
+		return err
+	}
+	if err := c.RenderChildren(n.Children, documentSeparator); err != nil {
+		return err
+	}
+
+	return c.renderer.DocumentEnd(c, n)
+}
+
+// Render calls the Renderer method for the kind of n.
+func (c *RenderContext) Render(n Node) error {
+	var err error
+
+	switch n := n.(type) {
+	case *Document:
+		err = c.renderDocument(n)
+	case *Paragraph:
+		err = c.renderer.Paragraph(c, n)
+	case *Heading:
+		err = c.renderer.Heading(c, n)
+	case *TextBlock:
+		err = c.renderer.TextBlock(c, n)
+	case *List:
+		err = c.renderer.List(c, n)
+	case *ListItem:
+		err = c.renderer.ListItem(c, n)
+	case *Blockquote:
+		err = c.renderer.Blockquote(c, n)
+	case *Callout:
+		err = c.renderer.Callout(c, n)
+	case *ThematicBreak:
+		err = c.renderer.ThematicBreak(c, n)
+	case *CodeBlock:
+		err = c.renderer.CodeBlock(c, n)
+	case *Table:
+		err = c.renderer.Table(c, n)
+	case *TableRow:
+		err = c.renderer.TableRow(c, n)
+	case *TableCell:
+		err = c.renderer.TableCell(c, n)
+	case *FootnoteDefinition:
+		err = c.renderer.FootnoteDefinition(c, n)
+	case *Text:
+		err = c.renderer.Text(c, n)
+	case *SoftBreak:
+		err = c.renderer.SoftBreak(c, n)
+	case *Emphasis:
+		err = c.renderer.Emphasis(c, n)
+	case *Strong:
+		err = c.renderer.Strong(c, n)
+	case *Strikethrough:
+		err = c.renderer.Strikethrough(c, n)
+	case *Highlight:
+		err = c.renderer.Highlight(c, n)
+	case *Superscript:
+		err = c.renderer.Superscript(c, n)
+	case *Subscript:
+		err = c.renderer.Subscript(c, n)
+	case *CodeSpan:
+		err = c.renderer.CodeSpan(c, n)
+	case *FootnoteReference:
+		err = c.renderer.FootnoteReference(c, n)
+	case *Link:
+		err = c.renderer.Link(c, n)
+	case *WikiLink:
+		err = c.renderer.WikiLink(c, n)
+	case *Image:
+		err = c.renderer.Image(c, n)
+	default:
+		err = fmt.Errorf("converter: cannot render node of type %T", n)
+	}
+
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+
+	return c.err
+}
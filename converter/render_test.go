@@ -0,0 +1,68 @@
+package converter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// figureRenderer changes the markup of images only.
+type figureRenderer struct {
+	HTMLRenderer
+}
+
+func (figureRenderer) Image(ctx *RenderContext, n *Image) error {
+	ctx.WriteString("<figure><img src=\"")
+	ctx.WriteString(ctx.Options().ImageDirectory + "/" + n.Name)
+	ctx.WriteString("\" loading=\"lazy\"></figure>")
+
+	return ctx.Err()
+}
+
+// failingRenderer fails on every heading.
+type failingRenderer struct {
+	HTMLRenderer
+}
+
+func (failingRenderer) Heading(ctx *RenderContext, n *Heading) error {
+	return errors.New("no headings allowed")
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRendererOverride(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Renderer = figureRenderer{}
+
+	input := "# Images\n\n- an image in a list ![[list.png]]\n\n![[image_name.png]]"
+	expected := "<h1 id=\"images\"> Images</h1>\n<p>\n<ul>\n<li> an image in a list <figure><img src=\"/directory_name/list.png\" loading=\"lazy\"></figure></li>\n</ul>\n</p>\n<p>\n<figure><img src=\"/directory_name/image_name.png\" loading=\"lazy\"></figure>\n</p>"
+
+	sb := strings.Builder{}
+	err := New(opts).Convert(strings.NewReader(input), &sb)
+	if err != nil {
+		t.Fatalf("TestRendererOverride unexpected error: %v", err)
+	}
+	if sb.String() != expected {
+		t.Errorf("TestRendererOverride expected: \n%s \nbut got: \n%s", expected, sb.String())
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Renderer = failingRenderer{}
+
+	sb := strings.Builder{}
+	err := New(opts).Convert(strings.NewReader("Text.\n\n# Heading\n\nMore text."), &sb)
+	if err == nil || err.Error() != "no headings allowed" {
+		t.Errorf("TestRenderErrors expected the renderer's error but got: %v", err)
+	}
+
+	err = New(DefaultOptions()).Convert(strings.NewReader("# Heading"), failingWriter{})
+	if err == nil || err.Error() != "disk full" {
+		t.Errorf("TestRenderErrors expected the writer's error but got: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package converter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NoteResolver gives the URL of a note named in a wikilink, such as
+// "Other Note" in [[Other Note]]. ok is false if there is no such note.
+type NoteResolver interface {
+	ResolveNote(name string) (url string, ok bool)
+}
+
+// SlugResolver links to every note at Prefix, followed by the slug of each
+// part of its path and Extension: [[Other Note]] links to
+// Prefix + "other-note" + Extension.
+type SlugResolver struct {
+	Prefix    string
+	Extension string
+}
+
+func (r SlugResolver) ResolveNote(name string) (string, bool) {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		parts[i] = Slugify(part)
+	}
+
+	return r.Prefix + strings.Join(parts, "/") + r.Extension, true
+}
+
+// RelativeResolver links to every note by its name, as a path relative to
+// the page, followed by Extension: [[Other Note]] links to
+// "Other%20Note" + Extension.
+type RelativeResolver struct {
+	Extension string
+}
+
+func (r RelativeResolver) ResolveNote(name string) (string, bool) {
+	return name + r.Extension, true
+}
+
+// IndexResolver maps the names of the notes which exist to their URLs.
+// Names are matched regardless of case, and any other note is unresolved.
+type IndexResolver map[string]string
+
+func (r IndexResolver) ResolveNote(name string) (string, bool) {
+	if url, ok := r[name]; ok {
+		return url, true
+	}
+	for note, url := range r {
+		if strings.EqualFold(note, name) {
+			return url, true
+		}
+	}
+
+	return "", false
+}
+
+// Slugify returns s in lower case, with spaces, '-' and '_' replaced by a
+// single '-' and any other punctuation removed, for use in a URL.
+func Slugify(s string) string {
+	sb := strings.Builder{}
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			dash = true
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,43 @@
+package converter
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	testCases := []struct {
+		input  string
+		output string
+	}{
+		{"Other Note", "other-note"},
+		{"  Spaces -- and_underscores  ", "spaces-and-underscores"},
+		{"What's new? (2024)", "whats-new-2024"},
+		{"Café Über", "café-über"},
+	}
+
+	for _, tst := range testCases {
+		if res := Slugify(tst.input); res != tst.output {
+			t.Errorf("TestSlugify expected %q for %q but got %q", tst.output, tst.input, res)
+		}
+	}
+}
+
+func TestNoteResolvers(t *testing.T) {
+	testCases := []struct {
+		name     string
+		resolver NoteResolver
+		note     string
+		url      string
+		ok       bool
+	}{
+		{"slugs should be joined with the prefix and extension", SlugResolver{Prefix: "/notes/", Extension: ".html"}, "Folder Name/Other Note", "/notes/folder-name/other-note.html", true},
+		{"relative links should keep the name", RelativeResolver{Extension: ".html"}, "Other Note", "Other Note.html", true},
+		{"the index should match regardless of case", IndexResolver{"Other Note": "/other/"}, "other note", "/other/", true},
+		{"the index should not resolve notes it does not have", IndexResolver{"Other Note": "/other/"}, "Missing", "", false},
+	}
+
+	for _, tst := range testCases {
+		url, ok := tst.resolver.ResolveNote(tst.note)
+		if url != tst.url || ok != tst.ok {
+			t.Errorf("TestNoteResolvers %s: expected (%q, %t) but got (%q, %t)", tst.name, tst.url, tst.ok, url, ok)
+		}
+	}
+}
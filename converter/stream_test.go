@@ -0,0 +1,127 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConvertStreams(t *testing.T) {
+	testCases := []struct {
+		name      string
+		paragraph string
+	}{
+		{name: "plain paragraphs", paragraph: "Paragraph %d.\n\n"},
+		{name: "paragraphs with undefined shortcut links", paragraph: "He said [sic] in paragraph %d.\n\n"},
+		{name: "paragraphs with reference links which are never defined", paragraph: "See [the docs][missing] in paragraph %d.\n\n"},
+	}
+
+	for _, tst := range testCases {
+		inR, inW := io.Pipe()
+		outR, outW := io.Pipe()
+
+		go func() {
+			err := New(DefaultOptions()).Convert(inR, outW)
+			outW.CloseWithError(err)
+		}()
+
+		// Write many paragraphs without closing the input, so that output
+		// can only be read if paragraphs are converted as they arrive.
+		go func() {
+			for i := range 2000 {
+				fmt.Fprintf(inW, tst.paragraph, i)
+			}
+		}()
+
+		read := make(chan error)
+		go func() {
+			_, err := io.ReadAtLeast(outR, make([]byte, 8192), 8192)
+			read <- err
+		}()
+
+		select {
+		case err := <-read:
+			if err != nil {
+				t.Fatalf("TestConvertStreams %s unexpected error: %v", tst.name, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("TestConvertStreams %s no output was written before the end of the input", tst.name)
+		}
+
+		inW.Close()
+		_, err := io.Copy(io.Discard, outR)
+		if err != nil {
+			t.Errorf("TestConvertStreams %s unexpected error: %v", tst.name, err)
+		}
+	}
+}
+
+func TestConvertLongParagraph(t *testing.T) {
+	// Inline parsing should take time in proportion to the length of the
+	// paragraph, so that a long one does not take minutes.
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{name: "emphasis", input: strings.Repeat("word *em* and _more_ ", 20000)},
+		{name: "unclosed inline links", input: strings.Repeat("[a](", 20000)},
+		{name: "unclosed link titles", input: strings.Repeat("[a](b (c ", 20000)},
+		{name: "unclosed wikilinks", input: strings.Repeat("[[a ", 100000)},
+		{name: "unclosed brackets", input: strings.Repeat("[a ", 100000)},
+		{name: "unclosed highlights", input: strings.Repeat("==a ", 100000)},
+		{name: "unclosed strikethrough", input: strings.Repeat("~~a ", 100000)},
+		{name: "nested brackets", input: strings.Repeat("[a", 20000) + strings.Repeat("]", 20000) + "("},
+	}
+
+	for _, tst := range testCases {
+		done := make(chan error)
+		go func() {
+			done <- New(DefaultOptions()).Convert(strings.NewReader(tst.input), io.Discard)
+		}()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("TestConvertLongParagraph %s unexpected error: %v", tst.name, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("TestConvertLongParagraph %s timed out converting a long paragraph", tst.name)
+		}
+	}
+}
+
+// failingReader returns its text and then err.
+type failingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, f.err
+	}
+
+	return n, err
+}
+
+func TestConvertReturnsReadErrors(t *testing.T) {
+	readErr := errors.New("connection reset")
+	r := &failingReader{r: strings.NewReader("# Heading\n\nSome text"), err: readErr}
+
+	err := New(DefaultOptions()).Convert(r, io.Discard)
+	if !errors.Is(err, readErr) {
+		t.Errorf("TestConvertReturnsReadErrors expected the reader's error but got: %v", err)
+	}
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("TestConvertReturnsReadErrors expected a *ParseError but got: %v", err)
+	}
+	if expected := (Position{Line: 3, Column: 1}); parseErr.Position != expected {
+		t.Errorf("TestConvertReturnsReadErrors expected the error at %v but got %v", expected, parseErr.Position)
+	}
+}
@@ -0,0 +1,174 @@
+package converter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Typography is how much of the text's punctuation is replaced by its
+// typographic form.
+type Typography int
+
+const (
+	// TypographyOff leaves the text as it was written.
+	TypographyOff Typography = iota
+
+	// TypographyBasic turns "--" into an en dash, "---" into an em dash
+	// and "..." into an ellipsis. Single hyphens are left alone.
+	TypographyBasic
+
+	// TypographyFull also turns straight quotes into the curly quotes of
+	// Options.Quotes, and apostrophes into ’.
+	TypographyFull
+)
+
+// Quotes are the marks written in place of straight quotes: Open and Close
+// for double quotes, and OpenSingle and CloseSingle for single quotes.
+type Quotes struct {
+	Open        string
+	Close       string
+	OpenSingle  string
+	CloseSingle string
+}
+
+// EnglishQuotes are used when Options.Quotes is empty.
+var EnglishQuotes = Quotes{Open: "“", Close: "”", OpenSingle: "‘", CloseSingle: "’"}
+
+// localeQuotes maps a language to its quotes. French guillemets are set off
+// from the text they quote by a no-break space.
+var localeQuotes = map[string]Quotes{
+	"en": EnglishQuotes,
+	"de": {Open: "„", Close: "“", OpenSingle: "‚", CloseSingle: "‘"},
+	"fr": {Open: "«\u00a0", Close: "\u00a0»", OpenSingle: "‹\u00a0", CloseSingle: "\u00a0›"},
+	"es": {Open: "«", Close: "»", OpenSingle: "“", CloseSingle: "”"},
+	"it": {Open: "«", Close: "»", OpenSingle: "“", CloseSingle: "”"},
+}
+
+// LocaleQuotes returns the quotes of the language of locale, such as "de"
+// or "fr-CA", and whether it is one which is known.
+func LocaleQuotes(locale string) (Quotes, bool) {
+	language, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	language, _, _ = strings.Cut(language, "_")
+	quotes, ok := localeQuotes[language]
+
+	return quotes, ok
+}
+
+// typography returns the text written in place of the dash, ellipsis or
+// quote starting at src[i], and the index after it. Returns next == i if
+// the Typography option leaves src[i] as it is.
+func (ip *inlineParser) typography(i, to int) (string, int) {
+	opts := ip.p.opts
+	if opts.Typography == TypographyOff {
+		return "", i
+	}
+
+	switch r := ip.src[i]; r {
+	case '-':
+		n := ip.runLength(i, to, '-')
+		// A longer run is kept whole, rather than ending in a dash.
+		if n > 3 {
+			return string(ip.src[i : i+n]), i + n
+		}
+		// A word starting with "--" is more likely a command-line flag,
+		// such as --verbose, than a dash.
+		if n < 2 || isWordStart(ip.before(i)) && isWordRune(ip.after(i+n-1, to)) {
+			return "", i
+		}
+		if n == 2 {
+			return "–", i + n
+		}
+		return "—", i + n
+	case '.':
+		if n := ip.runLength(i, to, '.'); n != 3 {
+			return string(ip.src[i : i+n]), i + n
+		}
+		return "…", i + 3
+	case '"', '\'':
+		if opts.Typography != TypographyFull {
+			return "", i
+		}
+		quotes := opts.Quotes
+		if quotes == (Quotes{}) {
+			quotes = EnglishQuotes
+		}
+
+		// A quote opens if it starts a word, and closes otherwise.
+		before, after := ip.before(i), ip.after(i, to)
+		opens := isWordStart(before) && !unicode.IsSpace(after) && !strings.ContainsRune(".,;:!?)]}", after)
+		switch {
+		case r == '\'' && isWordRune(before) && isWordRune(after):
+			// An apostrophe, as in "don't".
+			return "’", i + 1
+		case r == '\'' && opens && unicode.IsDigit(after):
+			// An apostrophe starting an abbreviated year, as in '90s.
+			return "’", i + 1
+		case r == '"' && opens:
+			return quotes.Open, i + 1
+		case r == '"':
+			return quotes.Close, i + 1
+		case opens:
+			return quotes.OpenSingle, i + 1
+		default:
+			return quotes.CloseSingle, i + 1
+		}
+	}
+
+	return "", i
+}
+
+// markup is the punctuation which may surround a quote without changing
+// whether it opens or closes, as in *"quoted"*.
+const markup = "*_~=^`"
+
+// before returns the character before src[i], skipping markup, or a space
+// at the start of src.
+func (ip *inlineParser) before(i int) rune {
+	for j := i - 1; j >= 0; j-- {
+		if !strings.ContainsRune(markup, ip.src[j]) {
+			return ip.src[j]
+		}
+	}
+
+	return ' '
+}
+
+// after returns the character after src[i], skipping markup, or a space at
+// the end of src[:to].
+func (ip *inlineParser) after(i, to int) rune {
+	for j := i + 1; j < to; j++ {
+		if !strings.ContainsRune(markup, ip.src[j]) {
+			return ip.src[j]
+		}
+	}
+
+	return ' '
+}
+
+// isWordStart reports whether a word may start after r: r is a space or
+// punctuation which opens something, such as '('.
+func isWordStart(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune("([{<-–—/\"'“‘„‚«‹", r)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// typographyText applies the Typography option to the whole of s, for text
+// which is not otherwise parsed.
+func (p *parser) typographyText(s string) string {
+	ip := inlineParser{p: p, src: []rune(s)}
+	sb := strings.Builder{}
+	for i := 0; i < len(ip.src); {
+		if t, next := ip.typography(i, len(ip.src)); next > i {
+			sb.WriteString(t)
+			i = next
+			continue
+		}
+		sb.WriteRune(ip.src[i])
+		i++
+	}
+
+	return sb.String()
+}
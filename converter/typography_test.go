@@ -0,0 +1,23 @@
+package converter
+
+import "testing"
+
+func TestLocaleQuotes(t *testing.T) {
+	testCases := []struct {
+		locale string
+		quotes Quotes
+		ok     bool
+	}{
+		{"en", EnglishQuotes, true},
+		{"de-CH", Quotes{Open: "„", Close: "“", OpenSingle: "‚", CloseSingle: "‘"}, true},
+		{"fr_CA", Quotes{Open: "«\u00a0", Close: "\u00a0»", OpenSingle: "‹\u00a0", CloseSingle: "\u00a0›"}, true},
+		{"xx", Quotes{}, false},
+	}
+
+	for _, tst := range testCases {
+		quotes, ok := LocaleQuotes(tst.locale)
+		if quotes != tst.quotes || ok != tst.ok {
+			t.Errorf("TestLocaleQuotes expected %v, %t for %q but got %v, %t", tst.quotes, tst.ok, tst.locale, quotes, ok)
+		}
+	}
+}
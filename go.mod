@@ -0,0 +1,3 @@
+module github.com/CJoubertLocal/my_markdown_to_html_converter
+
+go 1.22
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/CJoubertLocal/my_markdown_to_html_converter/converter"
+)
+
+// runBuild converts every Markdown file under -in to an HTML file at the
+// same place under -out, and copies every other file across.
+func runBuild(args []string, stdout, stderr io.Writer) int {
+	flags := newCommandFlags(programName+" build", stderr)
+	in := flags.String("in", ".", "directory of Markdown files to convert")
+	out := flags.String("out", "public", "directory to write the HTML files to")
+
+	opts, code, ok := flags.parse(args)
+	if !ok {
+		return code
+	}
+
+	summary := buildDir(*in, *out, opts, stderr)
+	fmt.Fprintln(stdout, summary)
+
+	return summary.exitCode()
+}
+
+// buildSummary counts what buildDir did with each file.
+type buildSummary struct {
+	converted int
+	copied    int
+	skipped   int
+	failed    []error
+}
+
+func (s *buildSummary) String() string {
+	return fmt.Sprintf("converted %d, copied %d, skipped %d, failed %d", s.converted, s.copied, s.skipped, len(s.failed))
+}
+
+// exitCode returns exitIOError if any file could not be read or written,
+// otherwise exitParseError if any file could not be converted.
+func (s *buildSummary) exitCode() int {
+	code := exitOK
+	for _, err := range s.failed {
+		if c := exitCodeFor(err, exitIOError); code == exitOK || c == exitIOError {
+			code = c
+		}
+	}
+
+	return code
+}
+
+func (s *buildSummary) fail(err error, stderr io.Writer) {
+	fmt.Fprintln(stderr, err)
+	s.failed = append(s.failed, err)
+}
+
+// buildDir mirrors inDir into outDir. Hidden files and directories, such as
+// .obsidian, are skipped, as is outDir if it is inside inDir. A file which
+// fails is reported to stderr and the rest are still built.
+func buildDir(inDir, outDir string, opts converter.Options, stderr io.Writer) *buildSummary {
+	summary := &buildSummary{}
+
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		summary.fail(err, stderr)
+		return summary
+	}
+
+	err = filepath.WalkDir(inDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			summary.fail(err, stderr)
+			return nil
+		}
+
+		rel, err := filepath.Rel(inDir, path)
+		if err != nil {
+			summary.fail(err, stderr)
+			return nil
+		}
+
+		if d.IsDir() {
+			if absPath, _ := filepath.Abs(path); absPath == absOut {
+				return fs.SkipDir
+			}
+			if rel != "." && isHidden(d.Name()) {
+				summary.skipped++
+				return fs.SkipDir
+			}
+			if err := os.MkdirAll(filepath.Join(outDir, rel), 0o755); err != nil {
+				summary.fail(err, stderr)
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if isHidden(d.Name()) || !d.Type().IsRegular() {
+			summary.skipped++
+			return nil
+		}
+
+		converted, err := buildFile(inDir, outDir, rel, opts)
+		switch {
+		case err != nil:
+			summary.fail(err, stderr)
+		case converted:
+			summary.converted++
+		default:
+			summary.copied++
+		}
+
+		return nil
+	})
+	if err != nil {
+		summary.fail(err, stderr)
+	}
+
+	return summary
+}
+
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+func isMarkdown(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return true
+	}
+
+	return false
+}
+
+// outputPath returns where the file at rel under the input directory is
+// written under outDir: Markdown files get a .html extension.
+func outputPath(outDir, rel string) string {
+	if isMarkdown(rel) {
+		rel = strings.TrimSuffix(rel, filepath.Ext(rel)) + ".html"
+	}
+
+	return filepath.Join(outDir, rel)
+}
+
+// buildFile converts the file at rel under inDir if it is Markdown, and
+// copies it otherwise. converted reports which was done.
+func buildFile(inDir, outDir, rel string, opts converter.Options) (converted bool, err error) {
+	inPath := filepath.Join(inDir, rel)
+	outPath := outputPath(outDir, rel)
+
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return isMarkdown(rel), err
+	}
+
+	if isMarkdown(rel) {
+		return true, convertFile(inPath, outPath, nil, nil, opts)
+	}
+
+	return false, copyFile(inPath, outPath)
+}
+
+func copyFile(inPath, outPath string) error {
+	in, err := os.Open(inPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		return err
+	}
+
+	return out.Close()
+}
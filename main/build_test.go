@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunBuild(t *testing.T) {
+	in := t.TempDir()
+	out := filepath.Join(in, "public")
+
+	files := map[string]string{
+		"index.md":               "# Home",
+		"posts/first.markdown":   "Some *text*.",
+		"posts/images/photo.png": "not really a png",
+		"posts/bad.md":           "[^note]: A note.",
+		".obsidian/app.json":     "{}",
+		".DS_Store":              "",
+	}
+	for name, contents := range files {
+		path := filepath.Join(in, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdout := strings.Builder{}
+	stderr := strings.Builder{}
+	code := run([]string{"build", "-in", in, "-out", out}, strings.NewReader(""), &stdout, &stderr)
+
+	if code != exitParseError {
+		t.Errorf("TestRunBuild expected exit code %d but got %d", exitParseError, code)
+	}
+	if summary := "converted 2, copied 1, skipped 2, failed 1\n"; stdout.String() != summary {
+		t.Errorf("TestRunBuild expected summary: \n%s \nbut got: \n%s", summary, stdout.String())
+	}
+	if !strings.Contains(stderr.String(), filepath.Join(in, "posts", "bad.md")+":1:1: invalid footnote number") {
+		t.Errorf("TestRunBuild expected the failed file in stderr but got: \n%s", stderr.String())
+	}
+
+	expected := map[string]string{
+		"index.html":             "<h1 id=\"home\"> Home</h1>",
+		"posts/first.html":       "Some <i>text</i>.",
+		"posts/images/photo.png": "not really a png",
+	}
+	for name, contents := range expected {
+		res, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Errorf("TestRunBuild expected %s to be written: %v", name, err)
+			continue
+		}
+		if string(res) != contents {
+			t.Errorf("TestRunBuild expected %s to contain: \n%s \nbut got: \n%s", name, contents, res)
+		}
+	}
+
+	for _, name := range []string{"posts/bad.html", ".obsidian", ".DS_Store", "public"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err == nil {
+			t.Errorf("TestRunBuild expected %s not to be written", name)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/CJoubertLocal/my_markdown_to_html_converter/converter"
+)
+
+// config is the JSON file given with -config. Fields left out of the file
+// keep their default values.
+type config struct {
+	ImageDirectory string `json:"imageDirectory"`
+	TableClass     string `json:"tableClass"`
+	FigureClass    string `json:"figureClass"`
+	Entities       bool   `json:"entities"`
+	Footnotes      bool   `json:"footnotes"`
+	Tables         bool   `json:"tables"`
+	Images         bool   `json:"images"`
+	Strikethrough  bool   `json:"strikethrough"`
+	Highlights     bool   `json:"highlights"`
+	Superscripts   bool   `json:"superscripts"`
+	Subscripts     bool   `json:"subscripts"`
+	Callouts       bool   `json:"callouts"`
+
+	// CalloutClasses is added to the default classes, replacing those of
+	// the same callout types.
+	CalloutClasses map[string]string `json:"calloutClasses"`
+
+	Wikilinks bool `json:"wikilinks"`
+
+	// WikilinkStyle is how wikilinks are turned into URLs: "relative", such
+	// as Other%20Note.html, or "slug", such as other-note.html.
+	WikilinkStyle string `json:"wikilinkStyle"`
+
+	// WikilinkIndex, if given, maps the name of every note to its URL.
+	// Wikilinks to any other note are unresolved.
+	WikilinkIndex map[string]string `json:"wikilinkIndex"`
+
+	// Typography is "off", "basic" for dashes and ellipses, or "full" for
+	// curly quotes too.
+	Typography string `json:"typography"`
+
+	// Quotes is the language whose quotes "full" typography uses, such as
+	// "en", "de" or "fr".
+	Quotes string `json:"quotes"`
+}
+
+func defaultConfig() config {
+	opts := converter.DefaultOptions()
+
+	return config{
+		ImageDirectory: opts.ImageDirectory,
+		TableClass:     opts.TableClass,
+		FigureClass:    opts.FigureClass,
+		Entities:       opts.Entities,
+		Typography:     "basic",
+		Quotes:         "en",
+		Footnotes:      opts.Footnotes,
+		Tables:         opts.Tables,
+		Images:         opts.Images,
+		Strikethrough:  opts.Strikethrough,
+		Highlights:     opts.Highlights,
+		Superscripts:   opts.Superscripts,
+		Subscripts:     opts.Subscripts,
+		Callouts:       opts.Callouts,
+		CalloutClasses: opts.CalloutClasses,
+		Wikilinks:      opts.Wikilinks,
+		WikilinkStyle:  "relative",
+	}
+}
+
+// loadConfig reads the config file at path. Unknown fields are an error, so
+// that a misspelt option is not silently ignored.
+func loadConfig(path string) (config, error) {
+	cfg := defaultConfig()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return cfg, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return cfg, err
+	}
+
+	switch cfg.WikilinkStyle {
+	case "slug", "relative":
+	default:
+		return cfg, fmt.Errorf("unknown wikilinkStyle %q", cfg.WikilinkStyle)
+	}
+	if _, ok := typographies[cfg.Typography]; !ok {
+		return cfg, fmt.Errorf("unknown typography %q", cfg.Typography)
+	}
+	if _, ok := converter.LocaleQuotes(cfg.Quotes); !ok {
+		return cfg, fmt.Errorf("unknown quotes %q", cfg.Quotes)
+	}
+
+	return cfg, nil
+}
+
+// typographies maps the typography field to the Typography it stands for.
+var typographies = map[string]converter.Typography{
+	"off":   converter.TypographyOff,
+	"basic": converter.TypographyBasic,
+	"full":  converter.TypographyFull,
+}
+
+func (cfg config) options() converter.Options {
+	quotes, _ := converter.LocaleQuotes(cfg.Quotes)
+
+	return converter.Options{
+		ImageDirectory: cfg.ImageDirectory,
+		TableClass:     cfg.TableClass,
+		FigureClass:    cfg.FigureClass,
+		Entities:       cfg.Entities,
+		Typography:     typographies[cfg.Typography],
+		Quotes:         quotes,
+		Footnotes:      cfg.Footnotes,
+		Tables:         cfg.Tables,
+		Images:         cfg.Images,
+		Strikethrough:  cfg.Strikethrough,
+		Highlights:     cfg.Highlights,
+		Superscripts:   cfg.Superscripts,
+		Subscripts:     cfg.Subscripts,
+		Callouts:       cfg.Callouts,
+		CalloutClasses: cfg.CalloutClasses,
+		Wikilinks:      cfg.Wikilinks,
+		NoteResolver:   cfg.noteResolver(),
+	}
+}
+
+func (cfg config) noteResolver() converter.NoteResolver {
+	switch {
+	case cfg.WikilinkIndex != nil:
+		return converter.IndexResolver(cfg.WikilinkIndex)
+	case cfg.WikilinkStyle == "slug":
+		return converter.SlugResolver{Extension: ".html"}
+	}
+
+	return converter.RelativeResolver{Extension: ".html"}
+}
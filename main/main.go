@@ -1,759 +1,224 @@
 package main
 
 import (
-	"bytes"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
 	"os"
-	"slices"
-	"strconv"
-	"strings"
+
+	"github.com/CJoubertLocal/my_markdown_to_html_converter/converter"
 )
 
-var htmlEntityMap = map[rune]string{
-	'\'': "&apos;",
-	'<':  "&lt;",
-	'>':  "&gt;",
-	'"':  "&quot;",
-	'-':  "&ndash;",
-}
+const programName = "my_markdown_to_html_converter"
 
-// Default directory name. To be overwritten by user with
-// a command-line flag.
-var imageDirectoryName = "/directory_name"
-var footnoteNumberMap = map[int]int{}
-var inlineFootnoteNumber int
+// version is set when building a release, with
+// -ldflags "-X main.version=v1.2.3".
+var version = "dev"
 
-func main() {
-	pathName := os.Args
-	br := getByteReadForFile(pathName[1])
-	res := convertMarkdownFileToBlogHTML(br, pathName[3])
-	saveToFile(res, pathName[2])
-}
-
-func getByteReadForFile(pathAndFilename string) *bytes.Reader {
-	bytesReadIn, err := os.ReadFile(pathAndFilename)
-	if err != nil {
-		log.Fatal("unable to find file:", err)
-	}
+// Exit codes.
+const (
+	exitOK         = 0
+	exitParseError = 1 // the Markdown could not be converted
+	exitUsage      = 2 // bad flags or config file
+	exitIOError    = 3 // a file could not be read or written
+)
 
-	// remove carriage returns
-	bytesReadIn = bytes.ReplaceAll(bytesReadIn, []byte{'\r'}, []byte{})
+const usageText = `Usage:
+  %[1]s [flags]              convert one Markdown file
+  %[1]s build [flags]        convert a directory of Markdown files
+  %[1]s watch [flags]        convert a file or directory again whenever it changes
+  %[1]s serve [flags]        preview a directory of Markdown files in a browser
 
-	return bytes.NewReader(bytesReadIn)
-}
+Flags:
+`
 
-// https://gobyexample.com/writing-files
-func saveToFile(res string, outputPathAndFileName string) {
-	f, err := os.Create(outputPathAndFileName)
-	if err != nil {
-		log.Fatal("unable to create file:", err)
-	}
-	defer f.Close()
+const exitCodesText = `
+Exit codes:
+  0  success
+  1  the Markdown could not be converted
+  2  bad flags or config file
+  3  a file could not be read or written
+`
 
-	numBytesWritten, err := f.WriteString(res)
-	if err != nil {
-		log.Fatal("error when writing to file:", err)
-	}
-	fmt.Printf("wrote %d bytes to file", numBytesWritten)
-
-	f.Sync()
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
 
-func convertMarkdownFileToBlogHTML(br *bytes.Reader, newImageDirectoryName string) string {
-	sb := strings.Builder{}
+// run carries out the command given by args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "build":
+			return runBuild(args[1:], stdout, stderr)
+		case "watch":
+			return runWatch(args[1:], stdout, stderr)
+		case "serve":
+			return runServe(args[1:], stdout, stderr)
+		}
+	}
 
-	lastCharacterWasANewLine := false
-	thereIsAParagraphToClose := false
-	addNewLineCharBeforeOpeningPara := false
-	inlineFootnoteNumber = 0
-	imageDirectoryName = newImageDirectoryName
+	flags := newCommandFlags(programName, stderr)
+	in := flags.String("in", "-", "Markdown file to convert, or - for standard input")
+	out := flags.String("out", "-", "HTML file to write, or - for standard output")
+	showVersion := flags.Bool("version", false, "print the version and exit")
 
-	for {
-		r, _, err := br.ReadRune()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatal("unable to read rune:", err)
-		}
+	opts, code, ok := flags.parse(args)
+	if !ok {
+		return code
+	}
 
-		switch r {
-		case '#':
-			addHeaderTagsOrPoundRune(br, &sb, lastCharacterWasANewLine)
-			lastCharacterWasANewLine = true
-			addNewLineCharBeforeOpeningPara = true
-
-		case ' ':
-			sb.WriteRune(r)
-			lastCharacterWasANewLine = false
-
-		case '\n':
-			addNewLine(br, &sb, &thereIsAParagraphToClose, &lastCharacterWasANewLine, &addNewLineCharBeforeOpeningPara)
-			lastCharacterWasANewLine = true
-
-		case '*':
-			addItalicsAndOrBoldTags(br, &sb)
-			lastCharacterWasANewLine = false
-
-		case '\'':
-			sb.WriteString(htmlEntityMap[r])
-			lastCharacterWasANewLine = false
-
-		case '<':
-			sb.WriteString(htmlEntityMap[r])
-			lastCharacterWasANewLine = false
-
-		case '>':
-			sb.WriteString(htmlEntityMap[r])
-			lastCharacterWasANewLine = false
-
-		case '"':
-			sb.WriteString(htmlEntityMap[r])
-			lastCharacterWasANewLine = false
-
-		case '-':
-			if lastCharacterWasANewLine {
-				addUnorderedList(br, &sb)
-				addNewLineCharBeforeOpeningPara = true
-				lastCharacterWasANewLine = true
-
-			} else {
-				sb.WriteString(htmlEntityMap[r])
-				lastCharacterWasANewLine = false
-			}
-
-		case '`':
-			addCodeBlock(br, &sb)
-			lastCharacterWasANewLine = false
-
-		case '[':
-			addFootNote(br, &sb)
-			lastCharacterWasANewLine = false
-
-		case '|':
-			addTable(br, &sb)
-			lastCharacterWasANewLine = true
-			addNewLineCharBeforeOpeningPara = true
-
-		case '!':
-			addImageTags(br, &sb)
-			lastCharacterWasANewLine = true
-			addNewLineCharBeforeOpeningPara = true
-
-		default:
-			lastCharacterWasANewLine = false
-			sb.WriteRune(r)
-		}
+	if *showVersion {
+		fmt.Fprintf(stdout, "%s %s\n", programName, version)
+		return exitOK
 	}
 
-	if thereIsAParagraphToClose {
-		sb.WriteRune('\n')
-		sb.WriteString("</p>")
+	err := convertFile(*in, *out, stdin, stdout, opts)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitCodeFor(err, exitIOError)
 	}
 
-	return sb.String()
+	return exitOK
 }
 
-func addHeaderTagsOrPoundRune(br *bytes.Reader, sb *strings.Builder, lastCharacterWasANewLine bool) {
-	if sb.Len() == 0 {
-		addHeaderTags(br, sb)
-
-	} else if lastCharacterWasANewLine {
-		addHeaderTags(br, sb)
-
-	} else {
-		sb.WriteRune('#')
-	}
+// commandFlags are the flags shared by every command.
+type commandFlags struct {
+	*flag.FlagSet
+	stderr     io.Writer
+	imageDir   *string
+	configPath *string
 }
 
-func addHeaderTags(br *bytes.Reader, sb *strings.Builder) {
-	var finishedCountingHeaderTagsForLine = false
-	var headerCount = 1 // assume 1, as a '#' has been seen in order to get to here
-	var nextR rune
-
-	for nextR != '\n' {
-		nextR, _, err := br.ReadRune()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatal("unable to read next rune in header:", err)
-		}
-		if !finishedCountingHeaderTagsForLine {
-			if nextR == '#' {
-				headerCount++
-			}
-			if nextR == ' ' {
-				finishedCountingHeaderTagsForLine = true
-				sb.WriteString("<h" + strconv.Itoa(headerCount) + ">")
-				sb.WriteRune(' ')
-			}
-
-		} else if nextR == '\n' {
-			break
-
-		} else {
-			addRuneOrHTMLEntity(nextR, sb)
-		}
+func newCommandFlags(name string, stderr io.Writer) *commandFlags {
+	flags := &commandFlags{
+		FlagSet: flag.NewFlagSet(name, flag.ContinueOnError),
+		stderr:  stderr,
 	}
-
-	sb.WriteString("</h" + strconv.Itoa(headerCount) + ">")
-}
-
-func addNewLine(br *bytes.Reader, sb *strings.Builder, thereIsAParagraphToClose *bool, lastCharacterWasANewLine *bool, thereIsAnUnorderedListOpen *bool) {
-	if *thereIsAParagraphToClose {
-		sb.WriteRune('\n')
-		sb.WriteString("</p>")
-		*thereIsAParagraphToClose = false
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, usageText, programName)
+		flags.PrintDefaults()
+		fmt.Fprint(stderr, exitCodesText)
 	}
-	if *lastCharacterWasANewLine {
-		nextR, _, err := br.ReadRune()
-		if err == io.EOF {
-			return
-		}
-		if err != nil {
-			log.Fatal("unable to read rune:", err)
-		}
-		if nextR == '[' {
-			err = br.UnreadRune()
-			if err != nil {
-				log.Fatal("unable to unread rune:", err)
-			}
-
-			sb.WriteRune('\n')
-			return
-		}
-		if *thereIsAnUnorderedListOpen {
-			sb.WriteRune('\n')
-			*thereIsAnUnorderedListOpen = false
-		}
-
-		sb.WriteString("<p>")
-		*thereIsAParagraphToClose = true
-
-		err = br.UnreadRune()
-		if err != nil {
-			log.Fatal("unable to unread rune:", err)
-		}
 
-	} else {
-		*lastCharacterWasANewLine = true
-	}
+	flags.imageDir = flags.String("image-dir", converter.DefaultImageDirectory, "directory prefixed to the name of every image")
+	flags.configPath = flags.String("config", "", "JSON file of converter options")
 
-	sb.WriteRune('\n')
+	return flags
 }
 
-// Optional extension: Extend to recursively allow for italics and bold text to exist within each other.
-func addItalicsAndOrBoldTags(br *bytes.Reader, sb *strings.Builder) {
-	asteriskCount := 1
-	asteriskCountNeededToCloseTags := 0
-	stillCountingAsterisks := true
-	italicsOrBoldTagOpen := false
-
-	for {
-		nextR, _, err := br.ReadRune()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatal("unable to read ahead by one rune when adding italics or bold tags:", err)
-		}
-
-		if nextR == '*' {
-			if stillCountingAsterisks {
-				asteriskCount++
-
-				if asteriskCount == asteriskCountNeededToCloseTags {
-					break
-				}
-
-			} else {
-				addRuneOrHTMLEntity(nextR, sb)
-			}
-		}
-
-		if nextR != '*' {
-			if !italicsOrBoldTagOpen {
-				stillCountingAsterisks = false
-
-				switch asteriskCount {
-				case 1:
-					sb.WriteString("<i>")
-					asteriskCountNeededToCloseTags = 1
-				case 2:
-					sb.WriteString("<b>")
-					asteriskCountNeededToCloseTags = 2
-				case 3:
-					sb.WriteString("<i><b>")
-					asteriskCountNeededToCloseTags = 3
-				}
-				asteriskCount = 0
-
-				addRuneOrHTMLEntity(nextR, sb)
-				italicsOrBoldTagOpen = true
-
-			} else {
-				stillCountingAsterisks = true
-				addRuneOrHTMLEntity(nextR, sb)
-			}
-		}
+// parse parses args and returns the converter options they give. If the
+// command should stop, ok is false and code is its exit code.
+func (flags *commandFlags) parse(args []string) (opts converter.Options, code int, ok bool) {
+	err := flags.Parse(args)
+	if err == flag.ErrHelp {
+		return opts, exitOK, false
 	}
-
-	switch asteriskCount {
-	case 1:
-		sb.WriteString("</i>")
-	case 2:
-		sb.WriteString("</b>")
-	case 3:
-		sb.WriteString("</b></i>")
+	if err != nil {
+		return opts, exitUsage, false
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(flags.stderr, "unexpected argument: %s\n", flags.Arg(0))
+		flags.Usage()
+		return opts, exitUsage, false
 	}
-}
-
-func addUnorderedList(br *bytes.Reader, sb *strings.Builder) {
-	var lastCharacterWasANewLine = false
-
-	sb.WriteString("<ul>")
-	sb.WriteRune('\n')
-	sb.WriteString("<li>")
 
-	for {
-		nextR, _, err := br.ReadRune()
-		if err == io.EOF {
-			sb.WriteString("</li>")
-			sb.WriteRune('\n')
-			break
-		}
+	cfg := defaultConfig()
+	if *flags.configPath != "" {
+		cfg, err = loadConfig(*flags.configPath)
 		if err != nil {
-			log.Fatal("unable to read rune when creating an unordered list:", err)
-		}
-
-		if nextR == '-' {
-			if lastCharacterWasANewLine {
-				sb.WriteString("<li>")
-
-			} else {
-				sb.WriteString(htmlEntityMap[nextR])
-			}
-			lastCharacterWasANewLine = false
-
-		} else if nextR == '\n' {
-			if lastCharacterWasANewLine {
-				err = br.UnreadRune()
-				if err != nil {
-					log.Fatal("unable to unread rune at end of unordered list:", err)
-				}
-				break
-
-			} else {
-				sb.WriteString("</li>")
-				sb.WriteRune('\n')
-				lastCharacterWasANewLine = true
-			}
-		} else if nextR == '[' {
-			addFootNote(br, sb)
-
-		} else if nextR == '*' {
-			addItalicsAndOrBoldTags(br, sb)
-
-		} else if nextR == '`' {
-			addCodeBlock(br, sb)
-
-		} else {
-			addRuneOrHTMLEntity(nextR, sb)
-			lastCharacterWasANewLine = false
+			fmt.Fprintln(flags.stderr, "unable to read config file:", err)
+			return opts, exitCodeFor(err, exitUsage), false
 		}
 	}
+	if flagWasSet(flags.FlagSet, "image-dir") {
+		cfg.ImageDirectory = *flags.imageDir
+	}
 
-	sb.WriteString("</ul>")
+	return cfg.options(), exitOK, true
 }
 
-func addCodeBlock(br *bytes.Reader, sb *strings.Builder) {
-	var numberOfCurrentBackQuotes = 1
-	var thereIsACodeBlockOpen = false
-
-	for {
-		nextR, _, err := br.ReadRune()
-		if err == io.EOF {
-			if thereIsACodeBlockOpen {
-				sb.WriteString("</code>")
-				if numberOfCurrentBackQuotes == 6 {
-					sb.WriteString("</pre>")
-				}
-				thereIsACodeBlockOpen = false
-				numberOfCurrentBackQuotes = 0
-			}
-			return
-		}
-		if err != nil {
-			log.Fatal("unable to read next rune:", err)
+func flagWasSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
 		}
+	})
 
-		if nextR == '`' {
-			numberOfCurrentBackQuotes++
-
-			if thereIsACodeBlockOpen {
-				if numberOfCurrentBackQuotes == 2 {
-					sb.WriteString("</code>")
-					return
-				}
-			}
-
-			if numberOfCurrentBackQuotes == 3 {
-				sb.WriteString("<pre><code>")
-				thereIsACodeBlockOpen = true
-
-				for nextR != '\n' {
-					nextR, _, err = br.ReadRune()
-					if err == io.EOF {
-						break
-					}
-					if err != nil {
-						log.Fatal("unable to read next rune:", err)
-					}
-				}
-				sb.WriteRune('\n')
-			}
-
-			if numberOfCurrentBackQuotes == 6 {
-				sb.WriteString("</code></pre>")
-				return
-			}
-
-		} else {
-			if !thereIsACodeBlockOpen {
-				if numberOfCurrentBackQuotes == 1 {
-					sb.WriteString("<code>")
-					thereIsACodeBlockOpen = true
-
-				} else if numberOfCurrentBackQuotes == 2 {
-					sb.WriteString("</code>")
-					return
-				}
-			}
-
-			addRuneOrHTMLEntity(nextR, sb)
-		}
-	}
+	return set
 }
 
-func addRuneOrHTMLEntity(r rune, sb *strings.Builder) {
-	if slices.Contains([]rune{'\'', '<', '>', '"', '-'}, r) {
-		sb.WriteString(htmlEntityMap[r])
-	} else {
-		sb.WriteRune(r)
-	}
-}
+// exitCodeFor returns the exit code for err: exitParseError for bad
+// Markdown, exitIOError for files which cannot be read or written, and
+// otherwise the given code.
+func exitCodeFor(err error, otherwise int) int {
+	var parseErr *converter.ParseError
+	var pathErr *fs.PathError
 
-func addFootNote(br *bytes.Reader, sb *strings.Builder) {
-	inTextFootnoteNumber := strings.Builder{}
-	for {
-		nextR, _, err := br.ReadRune()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatal("unable to read next rune:", err)
-		}
-		if nextR == ']' {
-
-			nextR, _, err = br.ReadRune()
-			if err == io.EOF {
-				sb.WriteString(
-					"<a id=\"footnote-anchor-" +
-						strconv.Itoa(inlineFootnoteNumber) +
-						"\" href=\"#footnote-" +
-						strconv.Itoa(inlineFootnoteNumber) +
-						"\">[" + strconv.Itoa(inlineFootnoteNumber) +
-						"]</a>",
-				)
-				break
-			}
-			if err != nil {
-				log.Fatal("unable to read next rune:", err)
-			}
-			if nextR == ':' {
-				footnoteNumber, err := strconv.Atoi(inTextFootnoteNumber.String())
-				if err != nil {
-					log.Fatal("unable to convert string to number:", err)
-				}
-
-				sb.WriteString(
-					"<p id=\"footnote-" +
-						strconv.Itoa(footnoteNumberMap[footnoteNumber]) +
-						"\">\n<a href=\"#footnote-anchor-" +
-						strconv.Itoa(footnoteNumberMap[footnoteNumber]) +
-						"\">[" +
-						strconv.Itoa(footnoteNumberMap[footnoteNumber]) +
-						"]</a>",
-				)
-				sb.WriteRune('\n')
-
-				for nextR != '\n' {
-					nextR, _, err = br.ReadRune()
-					if err == io.EOF {
-						sb.WriteRune('\n')
-						break
-					}
-					if err != nil {
-						log.Fatal("unable to read next rune:", err)
-					}
-
-					addRuneOrHTMLEntity(nextR, sb)
-				}
-
-				sb.WriteString("</p>")
-				if err == io.EOF {
-					break
-				}
-				sb.WriteRune('\n')
-
-			} else {
-				sb.WriteString(
-					"<a id=\"footnote-anchor-" +
-						strconv.Itoa(inlineFootnoteNumber) +
-						"\" href=\"#footnote-" +
-						strconv.Itoa(inlineFootnoteNumber) +
-						"\">[" + strconv.Itoa(inlineFootnoteNumber) +
-						"]</a>",
-				)
-
-				footnoteOriginalNumber, err := strconv.Atoi(inTextFootnoteNumber.String())
-				if err != nil {
-					log.Fatal("unable to convert string to number:", err)
-				}
-				footnoteNumberMap[footnoteOriginalNumber] = inlineFootnoteNumber
-
-				err = br.UnreadRune()
-				if err != nil {
-					log.Fatal("unable to unread rune:", err)
-				}
-			}
-
-			break
-		} else if nextR == '^' {
-			inlineFootnoteNumber++
-
-		} else if nextR != '^' {
-			inTextFootnoteNumber.WriteRune(nextR)
-		}
+	// A ParseError may wrap the error from reading the file.
+	switch {
+	case errors.As(err, &pathErr):
+		return exitIOError
+	case errors.As(err, &parseErr):
+		return exitParseError
 	}
-}
-
-func addTable(br *bytes.Reader, sb *strings.Builder) {
-	sb.WriteString("<table class=\"table is-hoverable\">")
-	sb.WriteRune('\n')
 
-	addTableHeader(br, sb)
-	skipTableHeaderLine(br)
-	addTableBody(br, sb)
-
-	sb.WriteString("</table>")
+	return otherwise
 }
 
-func addTableHeader(br *bytes.Reader, sb *strings.Builder) {
-	sb.WriteString("<thead>")
-	sb.WriteRune('\n')
-	sb.WriteString("<tr>")
-	sb.WriteRune('\n')
-	sb.WriteString("<th>")
-
-	var nextR rune
-	var err error
-	for nextR != '\n' {
-		nextR, _, err = br.ReadRune()
-		if err == io.EOF {
-			break
-		}
+// convertFile converts inPath to outPath, where "-" means stdin or stdout.
+// Conversion errors are prefixed with the name of the input.
+func convertFile(inPath, outPath string, stdin io.Reader, stdout io.Writer, opts converter.Options) error {
+	name := "<stdin>"
+	r := stdin
+	if inPath != "-" {
+		f, err := os.Open(inPath)
 		if err != nil {
-			log.Fatal("unable to read next rune:", err)
-		}
-		if nextR == '|' {
-			nextR, _, err = br.ReadRune()
-			if err == io.EOF {
-				sb.WriteString("</th>")
-				sb.WriteRune('\n')
-				break
-			}
-			if err != nil {
-				log.Fatal("unable to read next rune:", err)
-			}
-			if nextR == '\n' {
-				sb.WriteString("</th>")
-				sb.WriteRune('\n')
-				break
-			}
-			err = br.UnreadRune()
-			if err != nil {
-				log.Fatal("unable to unread rune:", err)
-			}
-			sb.WriteString("</th>")
-			sb.WriteRune('\n')
-			sb.WriteString("<th>")
-
-		} else {
-			addRuneOrHTMLEntity(nextR, sb)
+			return fmt.Errorf("unable to find file: %w", err)
 		}
+		defer f.Close()
+		name, r = inPath, f
 	}
-	sb.WriteString("</tr>")
-	sb.WriteRune('\n')
-	sb.WriteString("</thead>")
-	sb.WriteRune('\n')
-}
 
-func skipTableHeaderLine(br *bytes.Reader) {
-	var afterR rune
-	var err error
-
-	for afterR != '\n' {
-		afterR, _, err = br.ReadRune()
-		if err == io.EOF {
-			break
-		}
+	var outFile *os.File
+	w := stdout
+	if outPath != "-" {
+		f, err := os.Create(outPath)
 		if err != nil {
-			log.Fatal("unable to read rune:", err)
+			return fmt.Errorf("unable to create file: %w", err)
 		}
+		defer f.Close()
+		outFile, w = f, f
 	}
-}
 
-func addTableBody(br *bytes.Reader, sb *strings.Builder) {
-	sb.WriteString("<tbody>")
-	sb.WriteRune('\n')
-
-	nextR, _, err := br.ReadRune()
-	if err == io.EOF {
-		sb.WriteString("</tbody>")
-		sb.WriteRune('\n')
-		return
-	}
-	if err != nil {
-		log.Fatal("unable to read next rune:", err)
-	}
-	if nextR == '|' {
-		sb.WriteString("<tr>")
-		sb.WriteRune('\n')
-		sb.WriteString("<td>")
-
-		numOfConsecutiveNewLines := 0
-
-		for numOfConsecutiveNewLines < 2 {
-			nextR, _, err = br.ReadRune()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				log.Fatal("unable to read next rune:", err)
-			}
-			if nextR == '\n' {
-				numOfConsecutiveNewLines++
-
-				if numOfConsecutiveNewLines < 2 {
-					sb.WriteString("</tr>")
-					sb.WriteRune('\n')
-				}
-
-				if numOfConsecutiveNewLines == 2 {
-					err = br.UnreadRune()
-					if err != nil {
-						log.Fatal("unable to unread new line character after table:", err)
-					}
-					break
-				}
-
-			} else {
-				if numOfConsecutiveNewLines == 1 {
-					sb.WriteString("<tr>")
-					sb.WriteRune('\n')
-					sb.WriteString("<td>")
-
-				} else {
-					if nextR == '|' {
-						sb.WriteString("</td>")
-
-						nextR, _, err = br.ReadRune()
-						if err == io.EOF {
-							sb.WriteRune('\n')
-							sb.WriteString("</tr>")
-							sb.WriteRune('\n')
-							break
-						}
-						if err != nil {
-							log.Fatal("unable to read next rune:", err)
-						}
-						sb.WriteRune('\n')
-						if nextR != '\n' {
-							sb.WriteString("<td>")
-						}
-						err = br.UnreadRune()
-						if err != nil {
-							log.Fatal("unable to unread rune:", err)
-						}
-
-					} else {
-						addRuneOrHTMLEntity(nextR, sb)
-					}
-				}
-				numOfConsecutiveNewLines = 0
-			}
-		}
+	err := converter.New(opts).Convert(r, w)
+	if err != nil && outFile != nil {
+		// Do not leave half a page behind.
+		outFile.Close()
+		os.Remove(outPath)
 	}
 
-	sb.WriteString("</tbody>")
-	sb.WriteRune('\n')
-}
-
-func addImageTags(br *bytes.Reader, sb *strings.Builder) {
-	// Assumes structure of ![[image_name.png]]
-	nextR, _, err := br.ReadRune()
-	if err == io.EOF {
-		sb.WriteRune('!')
-		return
-	}
 	if err != nil {
-		log.Fatal("unable to read rune:", err)
+		return withFileName(name, err)
 	}
-	if nextR != '[' {
-		sb.WriteRune('!')
-		err = br.UnreadRune()
-		if err != nil {
-			log.Fatal("unable to unread rune:", err)
-		}
-		return
+
+	if outFile != nil {
+		return outFile.Sync()
 	}
 
-	var imageNameAndExtension = strings.Builder{}
+	return nil
+}
 
-	for nextR != '\n' {
-		nextR, _, err = br.ReadRune()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatal("unable to read rune:", err)
-		}
-		if nextR != '[' && nextR != ']' {
-			imageNameAndExtension.WriteRune(nextR)
-		}
-		if nextR == ']' {
-			_, _, err = br.ReadRune()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				log.Fatal("unable to read last ] of an image:", err)
-			}
-			break
-		}
-	}
-	_, _, err = br.ReadRune()
-	if err != nil && err != io.EOF {
-		log.Fatal("unable to read rune:", err)
+// withFileName prefixes a conversion error with the name of the input, so
+// that a ParseError reads as name:line:column.
+func withFileName(name string, err error) error {
+	var parseErr *converter.ParseError
+	if errors.As(err, &parseErr) {
+		return fmt.Errorf("%s:%w", name, err)
 	}
 
-	sb.WriteString("<figure class=\"image\">")
-	sb.WriteRune('\n')
-	sb.WriteString("<img src=\"" + imageDirectoryName + "/" + imageNameAndExtension.String() + "\">")
-	sb.WriteRune('\n')
-	sb.WriteString("</figure>")
+	return fmt.Errorf("%s: %w", name, err)
 }
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CJoubertLocal/my_markdown_to_html_converter/converter"
+)
+
+// eventsPath is where browsers listen for reloads.
+const eventsPath = "/_events"
+
+// defaultPageTemplate is used to wrap each page unless -template is given.
+const defaultPageTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+{{.Content}}
+{{.Reload}}
+</body>
+</html>
+`
+
+// reloadScript reloads the page when the server sends a reload event.
+const reloadScript = `<script>new EventSource("` + eventsPath + `").addEventListener("reload", () => location.reload());</script>`
+
+// runServe serves the Markdown files under -in as HTML pages, converting
+// each one when it is requested, until interrupted.
+func runServe(args []string, stdout, stderr io.Writer) int {
+	flags := newCommandFlags(programName+" serve", stderr)
+	in := flags.String("in", ".", "directory of Markdown files to serve")
+	images := flags.String("images", "", "directory of images to serve under -image-dir (default: -in)")
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	templatePath := flags.String("template", "", "html/template file to wrap each page in")
+	interval := flags.Duration("interval", 500*time.Millisecond, "how often to check for changes")
+
+	opts, code, ok := flags.parse(args)
+	if !ok {
+		return code
+	}
+
+	page, err := loadPageTemplate(*templatePath)
+	if err != nil {
+		fmt.Fprintln(stderr, "unable to read template:", err)
+		return exitCodeFor(err, exitUsage)
+	}
+
+	if *images == "" {
+		*images = *in
+	}
+	s := newServer(*in, *images, opts, page)
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitIOError
+	}
+	fmt.Fprintf(stdout, "serving %s at http://%s/\n", *in, ln.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	go s.watch(ctx, *interval, stderr)
+
+	// Requests share ctx, so that open event streams end when interrupted
+	// rather than holding up Shutdown.
+	srv := &http.Server{
+		Handler:     s.handler(),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	err = srv.Serve(ln)
+	if err != nil && err != http.ErrServerClosed {
+		fmt.Fprintln(stderr, err)
+		return exitIOError
+	}
+
+	return exitOK
+}
+
+func loadPageTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return template.New("page").Parse(defaultPageTemplate)
+	}
+
+	return template.ParseFiles(path)
+}
+
+// pageData is given to the page template. Tasks counts the page's task
+// list items, and Reload is the script which reloads the page when its
+// source changes.
+type pageData struct {
+	Title   string
+	Path    string
+	Content template.HTML
+	Tasks   converter.TaskSummary
+	Reload  template.HTML
+}
+
+// server converts the Markdown files under content when they are requested,
+// and tells browsers to reload when any of them change.
+type server struct {
+	content string
+	images  string
+	opts    converter.Options
+	page    *template.Template
+	sources *watcher
+	reload  *reloader
+}
+
+func newServer(content, images string, opts converter.Options, page *template.Template) *server {
+	s := &server{
+		content: content,
+		images:  images,
+		opts:    opts,
+		page:    page,
+		sources: &watcher{in: content, dir: true, files: map[string]fileState{}},
+		reload:  &reloader{clients: map[chan struct{}]bool{}},
+	}
+	// Remember the files as they are now, so that only later changes
+	// cause a reload.
+	s.sources.scan(io.Discard)
+
+	return s
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(eventsPath, s.serveEvents)
+	if prefix, ok := imagePrefix(s.opts.ImageDirectory); ok {
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, http.FileServer(http.Dir(s.images))))
+	}
+	mux.HandleFunc("/", s.servePage)
+
+	return mux
+}
+
+// imagePrefix returns the URL path images are served under, or false if
+// the image directory is on another site or is the root.
+func imagePrefix(imageDir string) (string, bool) {
+	if strings.Contains(imageDir, "://") {
+		return "", false
+	}
+	prefix := path.Join("/", imageDir)
+
+	return prefix, prefix != "/"
+}
+
+// servePage converts the Markdown file for the requested path, or serves
+// the file itself if there is none. /post and /post.html are both
+// post.md, and a directory is its index.md.
+func (s *server) servePage(w http.ResponseWriter, r *http.Request) {
+	urlPath := path.Clean("/" + r.URL.Path)
+	for _, name := range strings.Split(urlPath, "/") {
+		if isHidden(name) {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	mdPath, ok := s.markdownPath(urlPath, strings.HasSuffix(r.URL.Path, "/"))
+	if !ok {
+		http.FileServer(http.Dir(s.content)).ServeHTTP(w, r)
+		return
+	}
+
+	f, err := os.Open(mdPath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	c := converter.New(s.opts)
+	doc, err := c.Parse(f)
+	if err != nil {
+		http.Error(w, withFileName(mdPath, err).Error(), http.StatusInternalServerError)
+		return
+	}
+	content := bytes.Buffer{}
+	if err := c.Render(&content, doc); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := pageData{
+		Title:   strings.TrimSuffix(filepath.Base(mdPath), filepath.Ext(mdPath)),
+		Path:    urlPath,
+		Content: template.HTML(content.String()),
+		Tasks:   doc.Tasks(),
+		Reload:  reloadScript,
+	}
+	page := bytes.Buffer{}
+	if err := s.page.Execute(&page, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	page.WriteTo(w)
+}
+
+// markdownPath returns the Markdown file to serve for urlPath, if any.
+func (s *server) markdownPath(urlPath string, dir bool) (string, bool) {
+	var candidates []string
+	switch ext := path.Ext(urlPath); {
+	case dir || urlPath == "/":
+		candidates = []string{path.Join(urlPath, "index.md"), path.Join(urlPath, "index.markdown")}
+	case ext == ".html":
+		base := strings.TrimSuffix(urlPath, ext)
+		candidates = []string{base + ".md", base + ".markdown"}
+	case ext == "":
+		candidates = []string{urlPath + ".md", urlPath + ".markdown"}
+	}
+
+	for _, candidate := range candidates {
+		mdPath := filepath.Join(s.content, filepath.FromSlash(candidate))
+		if info, err := os.Stat(mdPath); err == nil && info.Mode().IsRegular() {
+			return mdPath, true
+		}
+	}
+
+	return "", false
+}
+
+// serveEvents sends a reload event to the browser whenever a source file
+// changes, until the browser goes away.
+func (s *server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := s.reload.subscribe()
+	defer s.reload.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			fmt.Fprint(w, "event: reload\ndata: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// watch tells browsers to reload whenever a file under the content
+// directory changes, until ctx is done.
+func (s *server) watch(ctx context.Context, interval time.Duration, stderr io.Writer) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		changed, removed := s.sources.scan(stderr)
+		if len(changed) > 0 || len(removed) > 0 {
+			s.reload.broadcast()
+		}
+	}
+}
+
+// reloader keeps a channel for each browser listening for reloads.
+type reloader struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]bool
+}
+
+func (rl *reloader) subscribe() chan struct{} {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	rl.clients[ch] = true
+
+	return ch
+}
+
+func (rl *reloader) unsubscribe(ch chan struct{}) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	delete(rl.clients, ch)
+}
+
+// broadcast tells every browser to reload. A browser which has not yet
+// been sent the last reload is only sent one.
+func (rl *reloader) broadcast() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for ch := range rl.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
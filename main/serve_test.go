@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CJoubertLocal/my_markdown_to_html_converter/converter"
+)
+
+func newTestServer(t *testing.T, files map[string]string) (*server, string) {
+	t.Helper()
+
+	in := t.TempDir()
+	for name, contents := range files {
+		path := filepath.Join(in, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := converter.DefaultOptions()
+	opts.ImageDirectory = "/images"
+	page := template.Must(template.New("page").Parse("<title>{{.Title}}</title>{{with .Tasks}}{{if .Total}}<p>{{.Done}}/{{.Total}} done</p>{{end}}{{end}}{{.Content}}"))
+
+	return newServer(in, filepath.Join(in, "attachments"), opts, page), in
+}
+
+func TestServe(t *testing.T) {
+	s, in := newTestServer(t, map[string]string{
+		"index.md":              "# Home",
+		"posts/first.md":        "Some *text*.",
+		"posts/bad.md":          "[^note]: A note.",
+		"posts/notes.txt":       "plain text",
+		"todo.md":               "- [x] One\n- [ ] Two",
+		"attachments/photo.png": "not really a png",
+		".obsidian/app.json":    "{}",
+	})
+
+	tests := []struct {
+		path     string
+		status   int
+		expected string
+	}{
+		{"/", http.StatusOK, "<title>index</title><h1 id=\"home\"> Home</h1>"},
+		{"/posts/first.html", http.StatusOK, "<title>first</title>Some <i>text</i>."},
+		{"/posts/first", http.StatusOK, "<title>first</title>Some <i>text</i>."},
+		{"/posts/notes.txt", http.StatusOK, "plain text"},
+		{"/todo", http.StatusOK, "<title>todo</title><p>1/2 done</p><ul>"},
+		{"/images/photo.png", http.StatusOK, "not really a png"},
+		{"/posts/bad.html", http.StatusInternalServerError, filepath.Join(in, "posts", "bad.md") + ":1:1: invalid footnote number"},
+		{"/posts/missing.html", http.StatusNotFound, "404 page not found"},
+		{"/.obsidian/app.json", http.StatusNotFound, "404 page not found"},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		s.handler().ServeHTTP(rec, httptest.NewRequest("GET", test.path, nil))
+
+		if rec.Code != test.status {
+			t.Errorf("TestServe expected status %d for %s but got %d", test.status, test.path, rec.Code)
+		}
+		if res := strings.TrimSpace(rec.Body.String()); !strings.HasPrefix(res, test.expected) {
+			t.Errorf("TestServe expected for %s: \n%s \nbut got: \n%s", test.path, test.expected, res)
+		}
+	}
+}
+
+func TestServeReloads(t *testing.T) {
+	s, in := newTestServer(t, map[string]string{"index.md": "# Home"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.watch(ctx, 10*time.Millisecond, io.Discard)
+
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + eventsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if err := os.WriteFile(filepath.Join(in, "index.md"), []byte("# New home"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan string, 10)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			events <- scanner.Text()
+		}
+		close(events)
+	}()
+
+	select {
+	case line := <-events:
+		if line != "event: reload" {
+			t.Errorf("TestServeReloads expected a reload event but got: %s", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a reload event")
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/CJoubertLocal/my_markdown_to_html_converter/converter"
+)
+
+// runWatch converts -in, a file or a directory, and then converts it again
+// every time it changes, until interrupted.
+func runWatch(args []string, stdout, stderr io.Writer) int {
+	flags := newCommandFlags(programName+" watch", stderr)
+	in := flags.String("in", ".", "Markdown file or directory to watch")
+	out := flags.String("out", "", "HTML file or directory to write to (default: beside the Markdown file, or public for a directory)")
+	interval := flags.Duration("interval", 500*time.Millisecond, "how often to check for changes")
+
+	opts, code, ok := flags.parse(args)
+	if !ok {
+		return code
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := watch(ctx, *in, *out, *interval, opts, stdout, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitCodeFor(err, exitIOError)
+	}
+
+	return exitOK
+}
+
+// watch polls in every interval until ctx is done. Conversion errors are
+// reported to stderr and watching carries on.
+func watch(ctx context.Context, in, out string, interval time.Duration, opts converter.Options, stdout, stderr io.Writer) error {
+	info, err := os.Stat(in)
+	if err != nil {
+		return err
+	}
+
+	w := &watcher{in: in, out: out, dir: info.IsDir(), files: map[string]fileState{}}
+	if w.out == "" {
+		w.out = "public"
+		if !w.dir {
+			w.out = strings.TrimSuffix(in, filepath.Ext(in)) + ".html"
+		}
+	}
+
+	for {
+		w.update(opts, stdout, stderr)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// fileState is what watcher remembers about a file to tell if it changed.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+// watcher remembers the state of every file under in, a file or a
+// directory, so that only files which changed are converted again. The
+// output directory out, if any, is not watched.
+type watcher struct {
+	in    string
+	out   string
+	dir   bool
+	files map[string]fileState // by path
+}
+
+// update converts or copies every file which changed since the last call,
+// and removes the output of every file which was deleted.
+func (w *watcher) update(opts converter.Options, stdout, stderr io.Writer) {
+	changed, removed := w.scan(stderr)
+
+	for _, path := range changed {
+		if !w.dir {
+			if err := convertFile(path, w.out, nil, nil, opts); err != nil {
+				fmt.Fprintln(stderr, err)
+				continue
+			}
+			fmt.Fprintln(stdout, "converted", path)
+			continue
+		}
+
+		rel, _ := filepath.Rel(w.in, path)
+		converted, err := buildFile(w.in, w.out, rel, opts)
+		switch {
+		case err != nil:
+			fmt.Fprintln(stderr, err)
+		case converted:
+			fmt.Fprintln(stdout, "converted", path)
+		default:
+			fmt.Fprintln(stdout, "copied", path)
+		}
+	}
+
+	// A single file which has gone is most likely being saved by an editor,
+	// so its output is kept.
+	for _, path := range removed {
+		if !w.dir {
+			continue
+		}
+		rel, _ := filepath.Rel(w.in, path)
+		err := os.Remove(outputPath(w.out, rel))
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprintln(stderr, err)
+			continue
+		}
+		fmt.Fprintln(stdout, "removed", path)
+	}
+}
+
+// scan returns the files whose contents changed since the last scan, and
+// those which have gone. A file's contents are only read again when its
+// modification time or size has changed.
+func (w *watcher) scan(stderr io.Writer) (changed, removed []string) {
+	absOut, _ := filepath.Abs(w.out)
+	seen := map[string]bool{}
+
+	filepath.WalkDir(w.in, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return nil
+		}
+		if d.IsDir() {
+			absPath, _ := filepath.Abs(path)
+			if w.out != "" && absPath == absOut || path != w.in && isHidden(d.Name()) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if w.dir && isHidden(d.Name()) || !d.Type().IsRegular() {
+			return nil
+		}
+
+		seen[path] = true
+		info, err := d.Info()
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return nil
+		}
+
+		old, known := w.files[path]
+		if known && info.ModTime().Equal(old.modTime) && info.Size() == old.size {
+			return nil
+		}
+
+		hash, err := hashFile(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return nil
+		}
+		w.files[path] = fileState{modTime: info.ModTime(), size: info.Size(), hash: hash}
+		if !known || hash != old.hash {
+			changed = append(changed, path)
+		}
+
+		return nil
+	})
+
+	for path := range w.files {
+		if !seen[path] {
+			delete(w.files, path)
+			removed = append(removed, path)
+		}
+	}
+
+	return changed, removed
+}
+
+func hashFile(path string) ([sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+
+	f, err := os.Open(path)
+	if err != nil {
+		return hash, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return hash, err
+	}
+	copy(hash[:], h.Sum(nil))
+
+	return hash, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CJoubertLocal/my_markdown_to_html_converter/converter"
+)
+
+// syncBuilder is a strings.Builder which can be written to by one goroutine
+// and read by another.
+type syncBuilder struct {
+	mu sync.Mutex
+	sb strings.Builder
+}
+
+func (s *syncBuilder) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sb.Write(p)
+}
+
+func (s *syncBuilder) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sb.String()
+}
+
+// waitFor fails the test if cond is still false after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestWatch(t *testing.T) {
+	in := t.TempDir()
+	out := filepath.Join(in, "public")
+	post := filepath.Join(in, "post.md")
+	other := filepath.Join(in, "other.md")
+
+	// Each write moves the modification time on, as a fast test can write
+	// twice within the resolution of the file system's clock.
+	modTime := time.Now()
+	writeFile := func(path, contents string) {
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		modTime = modTime.Add(time.Second)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fileContains := func(path, contents string) func() bool {
+		return func() bool {
+			res, err := os.ReadFile(path)
+			return err == nil && string(res) == contents
+		}
+	}
+
+	writeFile(post, "First version.")
+	writeFile(other, "Other post.")
+
+	stdout := &syncBuilder{}
+	stderr := &syncBuilder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- watch(ctx, in, out, 10*time.Millisecond, converter.DefaultOptions(), stdout, stderr)
+	}()
+
+	waitFor(t, "the first conversion", fileContains(filepath.Join(out, "post.html"), "First version."))
+
+	writeFile(post, "Second version.")
+	waitFor(t, "the changed file to be converted", fileContains(filepath.Join(out, "post.html"), "Second version."))
+
+	writeFile(post, "Broken[^x] version.")
+	waitFor(t, "the error to be reported", func() bool {
+		return strings.Contains(stderr.String(), post+":1:7: invalid footnote number")
+	})
+
+	writeFile(post, "Fixed version.")
+	waitFor(t, "the fixed file to be converted", fileContains(filepath.Join(out, "post.html"), "Fixed version."))
+
+	// A save which does not change the contents should not convert again.
+	writeFile(other, "Other post.")
+
+	if err := os.Remove(post); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the output of the deleted file to be removed", func() bool {
+		_, err := os.Stat(filepath.Join(out, "post.html"))
+		return os.IsNotExist(err)
+	})
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("TestWatch unexpected error: %v", err)
+	}
+
+	if n := strings.Count(stdout.String(), "converted "+other); n != 1 {
+		t.Errorf("TestWatch expected the unchanged file to be converted once but it was converted %d times", n)
+	}
+}